@@ -0,0 +1,221 @@
+package vcnl40xx
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	// autoRangeHigh is the ambient count above which auto-ranging steps to a
+	// less sensitive range
+	autoRangeHigh = 0xE000
+	// autoRangeLow is the ambient count below which auto-ranging steps to a
+	// more sensitive range.  This is set below autoRangeHigh / 2 so a reading
+	// after stepping in does not immediately step back out.
+	autoRangeLow = 0x3800
+)
+
+// alsRange defines an ambient light measurement range
+type alsRange struct {
+	// it is the integration time in milliseconds
+	it uint16
+	// hd is the ALS_HD dynamic range on VCNL4030/VCNL4035
+	hd AmbientRange
+	// ns is the ALS_NS dynamic range on VCNL4030/VCNL4035
+	ns AmbientRange
+}
+
+// autoRange holds the state of ambient light auto-ranging
+type autoRange struct {
+	// enabled is set when auto-ranging is active
+	enabled bool
+	// index is the active range in alsRanges()
+	index int
+	// settle is the time a measurement in the active range is available
+	settle time.Time
+	// lastLux is the last lux value returned
+	lastLux float64
+	// measured is set once lastLux holds a measurement
+	measured bool
+}
+
+// EnableAutoRange turns on ambient light auto-ranging.  Each call to GetLux
+// checks the ambient count for saturation or underflow and steps the
+// integration time, and on VCNL4030/VCNL4035 the ALS_HD and ALS_NS dynamic
+// range, by one range at a time.  Lux is always reported normalised to the
+// range the reading was taken in.  The first call to GetLux after enabling
+// waits for a measurement in the selected range to complete.  Setting the
+// ambient integration time or dynamic range directly turns auto-ranging off.
+func (s *Sensor) EnableAutoRange() error {
+
+	s.rangeMu.Lock()
+	defer s.rangeMu.Unlock()
+
+	it, err := s.GetAmbientIntegrationTime()
+
+	if err != nil {
+		return err
+	}
+
+	hd, ns, err := s.ambientRanges(context.Background())
+
+	if err != nil {
+		return err
+	}
+
+	ranges := s.alsRanges()
+	index := 0
+
+	for i, r := range ranges {
+		if r == (alsRange{it, hd, ns}) {
+			index = i
+			break
+		}
+	}
+
+	if err := s.setAmbientRange(context.Background(), ranges[index]); err != nil {
+		return err
+	}
+
+	s.auto = autoRange{
+		enabled: true,
+		index:   index,
+		settle:  time.Now().Add(time.Duration(ranges[index].it) * time.Millisecond),
+	}
+
+	return nil
+}
+
+// DisableAutoRange turns off ambient light auto-ranging, leaving the sensor
+// in the range last selected
+func (s *Sensor) DisableAutoRange() {
+	s.rangeMu.Lock()
+	s.auto.enabled = false
+	s.rangeMu.Unlock()
+}
+
+// leaveAutoRange turns off auto-ranging if r is not the range it selected,
+// as a range set outside of auto-ranging leaves its state stale
+func (s *Sensor) leaveAutoRange(r alsRange) {
+
+	if s.model != VCNL4030 && s.model != VCNL4035 {
+		r.hd, r.ns = AmbientRange1, AmbientRange1
+	}
+
+	s.rangeMu.Lock()
+	defer s.rangeMu.Unlock()
+
+	if s.auto.enabled && s.alsRanges()[s.auto.index] != r {
+		s.auto.enabled = false
+	}
+}
+
+// autoRangeLux reads the ambient light value in lux and steps the range if
+// the reading is outside of the hysteresis band.  While a measurement in a
+// new range is in progress the last reading is returned.  The caller must
+// hold rangeMu.
+func (s *Sensor) autoRangeLux(ctx context.Context) (float64, error) {
+
+	// no measurement has completed in the new range yet, so return the last
+	// reading which was normalised to the range it was taken in
+	if time.Now().Before(s.auto.settle) && s.auto.measured {
+		return s.auto.lastLux, nil
+	}
+
+	ranges := s.alsRanges()
+	active := ranges[s.auto.index]
+
+	ambient, err := s.GetAmbientContext(ctx)
+
+	if err != nil {
+		return 0, err
+	}
+
+	lux := float64(ambient) * s.rangeResolution(active)
+	s.auto.lastLux = lux
+	s.auto.measured = true
+
+	next := s.auto.index
+
+	if ambient > autoRangeHigh && next < len(ranges)-1 {
+		next++
+	} else if ambient < autoRangeLow && next > 0 {
+		next--
+	}
+
+	if next != s.auto.index {
+
+		if err := s.setAmbientRange(ctx, ranges[next]); err != nil {
+			return lux, err
+		}
+
+		s.auto.index = next
+		// allow for the measurement in progress to complete before the
+		// first measurement in the new range
+		s.auto.settle = time.Now().Add(time.Duration(active.it+ranges[next].it) * time.Millisecond)
+	}
+
+	return lux, nil
+}
+
+// alsRanges returns the ambient light ranges from most to least sensitive
+func (s *Sensor) alsRanges() []alsRange {
+
+	x1, x2 := AmbientRange1, AmbientRange2
+
+	if s.model == VCNL4040 {
+		return []alsRange{{640, x1, x1}, {320, x1, x1}, {160, x1, x1}, {80, x1, x1}}
+	}
+
+	return []alsRange{
+		{800, x1, x1},
+		{400, x1, x1},
+		{200, x1, x1},
+		{100, x1, x1},
+		{50, x1, x1},
+		{50, x2, x1},
+		{50, x2, x2},
+	}
+}
+
+// setAmbientRange configures the sensor for the given ambient range.  The
+// register bits are written directly as the public setters turn auto-ranging
+// off.  The integration time and dynamic range share ALS_CONF, which is
+// written once so the sensor never measures with a mix of two ranges.
+func (s *Sensor) setAmbientRange(ctx context.Context, r alsRange) error {
+
+	if err := s.mu.LockContext(ctx); err != nil {
+		return err
+	}
+
+	defer s.mu.Unlock()
+
+	value, err := s.readRegister(ctx, s.cc.ALS_CONF)
+
+	if err != nil {
+		return err
+	}
+
+	itValue, _ := lookup(ambientITOptions(s.model, s.reg), r.it)
+	value = maskLower(value, s.reg.ALS_IT_MASK, itValue)
+
+	if s.model == VCNL4030 || s.model == VCNL4035 {
+		hdValue, _ := lookup(ambientHDOptions(s.reg), uint16(r.hd))
+		nsValue, _ := lookup(ambientNSOptions(s.reg), uint16(r.ns))
+
+		value = maskLower(value, s.reg.ALS_HD_MASK, hdValue)
+		value = maskUpper(value, s.reg.ALS_NS_MASK, nsValue)
+	}
+
+	return s.writeRegister(ctx, s.cc.ALS_CONF, value)
+}
+
+// rangeResolution returns the lux per count for the given ambient range
+func (s *Sensor) rangeResolution(r alsRange) float64 {
+
+	if s.model == VCNL4040 {
+		return luxPerCount4040 * luxIT4040 / float64(r.it)
+	}
+
+	return luxPerCount4030 * luxIT4030 / float64(r.it) * float64(r.hd) * float64(r.ns)
+}
@@ -0,0 +1,173 @@
+package vcnl40xx_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	vcnl "github.com/swdee/go-vcnl40xx"
+)
+
+// waitSettle waits for a measurement in a newly selected ambient range, the
+// longest settle time of the VCNL4040 steps used below
+func waitSettle() {
+	time.Sleep(250 * time.Millisecond)
+}
+
+func TestAutoRangeHysteresis(t *testing.T) {
+
+	s, dev := connect(t, vcnl.VCNL4040)
+
+	if err := s.SetAmbientIntegrationTime(80); err != nil {
+		t.Fatalf("error setting integration time: %v", err)
+	}
+
+	if err := s.EnableAutoRange(); err != nil {
+		t.Fatalf("error enabling auto-range: %v", err)
+	}
+
+	steps := []struct {
+		name    string
+		ambient uint16
+		// lux is the reading normalised to the range it was taken in
+		lux float64
+		// it is the integration time selected after the reading
+		it     uint16
+		settle bool
+	}{
+		{"below low threshold steps in", 0x3800 - 1, (0x3800 - 1) * 0.1, 160, true},
+		{"at high threshold is kept", 0xE000, 0xE000 * 0.05, 160, false},
+		{"at low threshold is kept", 0x3800, 0x3800 * 0.05, 160, false},
+		{"above high threshold steps out", 0xE000 + 1, (0xE000 + 1) * 0.05, 80, true},
+		{"least sensitive range is kept", 0xFFFF, 0xFFFF * 0.1, 80, false},
+	}
+
+	for _, st := range steps {
+
+		dev.SetAmbient(st.ambient)
+
+		lux, err := s.GetLux()
+
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", st.name, err)
+		}
+
+		if !closeTo(lux, st.lux) {
+			t.Errorf("%s: got %v lux, want %v", st.name, lux, st.lux)
+		}
+
+		if it, _ := s.GetAmbientIntegrationTime(); it != st.it {
+			t.Errorf("%s: integration time is %dms, want %dms", st.name, it, st.it)
+		}
+
+		if st.settle {
+			// readings during the settle time repeat the last value without
+			// reading the sensor
+			dev.ClearTransactions()
+
+			if again, _ := s.GetLux(); again != lux {
+				t.Errorf("%s: got %v lux while settling, want %v", st.name, again, lux)
+			}
+
+			if n := len(dev.Transactions()); n != 0 {
+				t.Errorf("%s: %d transactions while settling", st.name, n)
+			}
+
+			waitSettle()
+		}
+	}
+}
+
+func TestAutoRangeStepsWriteOnce(t *testing.T) {
+
+	cc := vcnl.CommandCodes4030()
+	r := vcnl.Registers4030()
+
+	s, dev := connect(t, vcnl.VCNL4030)
+
+	// start in the 100ms range, the step to 50ms x1 changes only the
+	// integration time
+	if err := s.SetAmbientIntegrationTime(100); err != nil {
+		t.Fatalf("error setting integration time: %v", err)
+	}
+
+	if err := s.EnableAutoRange(); err != nil {
+		t.Fatalf("error enabling auto-range: %v", err)
+	}
+
+	dev.SetAmbient(0xFFFF)
+	dev.ClearTransactions()
+
+	if _, err := s.GetLux(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	writes := dev.Writes()
+
+	if len(writes) != 1 || writes[0].Cmd != cc.ALS_CONF {
+		t.Fatalf("got writes %+v, want a single ALS_CONF write", writes)
+	}
+
+	// wait for the 50ms measurement, then step to ALS_HD x2
+	time.Sleep(200 * time.Millisecond)
+	dev.ClearTransactions()
+
+	if _, err := s.GetLux(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	writes = dev.Writes()
+
+	if len(writes) != 1 || writes[0].Cmd != cc.ALS_CONF {
+		t.Fatalf("got writes %+v, want a single ALS_CONF write", writes)
+	}
+
+	als := uint8(writes[0].Value)
+
+	if als&^r.ALS_IT_MASK != r.ALS_IT_50MS || als&^r.ALS_HD_MASK != r.ALS_HD_2 {
+		t.Errorf("ALS_CONF written as 0x%04X, want 50ms with ALS_HD x2", writes[0].Value)
+	}
+}
+
+func TestAutoRangeWaitDoesNotHoldLock(t *testing.T) {
+
+	s, _ := connect(t, vcnl.VCNL4030)
+
+	if err := s.SetAmbientIntegrationTime(800); err != nil {
+		t.Fatalf("error setting integration time: %v", err)
+	}
+
+	if err := s.EnableAutoRange(); err != nil {
+		t.Fatalf("error enabling auto-range: %v", err)
+	}
+
+	// the first reading waits up to 800ms for a measurement
+	done := make(chan error, 1)
+
+	go func() {
+		_, err := s.GetLux()
+		done <- err
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+
+	start := time.Now()
+
+	if err := s.Close(); err != nil {
+		t.Fatalf("error closing sensor: %v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed > 200*time.Millisecond {
+		t.Errorf("Close blocked for %v by the auto-range wait", elapsed)
+	}
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, vcnl.ErrNotConnected) {
+			t.Errorf("expected ErrNotConnected, got %v", err)
+		}
+
+	case <-time.After(2 * time.Second):
+		t.Fatal("GetLux did not return")
+	}
+}
@@ -0,0 +1,16 @@
+package vcnl40xx
+
+// Bus defines the I2C transport used by the Sensor to communicate with the
+// device.  The *i2c.Options type from github.com/swdee/go-i2c satisfies this
+// interface and is used by Connect, however any other implementation can be
+// passed to NewSensorWithBus or ConnectBus, such as a shared bus handle or
+// a simulated device for testing.
+type Bus interface {
+	// WriteBytes writes the given buffer to the device
+	WriteBytes(buf []byte) (int, error)
+	// WriteThenReadBytes writes the given buffer to the device then reads the
+	// response into readBuf in a single transaction
+	WriteThenReadBytes(writeBuf, readBuf []byte) (int, int, error)
+	// Close releases the bus
+	Close() error
+}
@@ -0,0 +1,112 @@
+package vcnl40xx
+
+import (
+	"context"
+)
+
+// EnableRegisterCache turns on the shadow copy of the configuration
+// registers (ALS_CONF, PS_CONF1-3, PS_MS, thresholds and PS_CANC).  With the
+// cache enabled read-modify-write of a register setting costs a single bus
+// write.  The cache is disabled by default, once enabled it is filled from
+// the sensor the first time each register is read.  Only enable it when no
+// other process configures the sensor, as settings changed behind the
+// driver's back are not seen until Refresh is called.
+func (s *Sensor) EnableRegisterCache() {
+	s.mu.Lock()
+	s.cache = true
+	s.mu.Unlock()
+}
+
+// DisableRegisterCache turns off the register shadow copy so every read goes
+// to the sensor, this is the default
+func (s *Sensor) DisableRegisterCache() {
+	s.mu.Lock()
+	s.cache = false
+	s.shadow = make(map[byte]uint16)
+	s.mu.Unlock()
+}
+
+// Refresh reloads the register cache from the sensor, use this if the
+// sensor configuration may have been changed outside of this driver.
+// Nothing is read if the register cache is disabled.
+func (s *Sensor) Refresh() error {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.cache {
+		return nil
+	}
+
+	shadow := make(map[byte]uint16)
+
+	for _, commandCode := range s.configRegisters() {
+
+		value, err := s.busRead(context.Background(), commandCode)
+
+		if err != nil {
+			return err
+		}
+
+		shadow[commandCode] = value
+	}
+
+	s.shadow = shadow
+
+	return nil
+}
+
+// Sync writes the contents of the register cache to the sensor, restoring
+// the last configuration set through this driver.  Nothing is written if the
+// register cache is disabled.
+func (s *Sensor) Sync() error {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, commandCode := range s.configRegisters() {
+
+		value, ok := s.shadow[commandCode]
+
+		if !ok {
+			continue
+		}
+
+		if err := s.writeRegister(context.Background(), commandCode, value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// configRegisters returns the command codes of the configuration registers
+func (s *Sensor) configRegisters() []byte {
+	return []byte{
+		s.cc.ALS_CONF, // and ALS_CONF2
+		s.cc.ALS_THDH,
+		s.cc.ALS_THDL,
+		s.cc.PS_CONF1, // and PS_CONF2
+		s.cc.PS_CONF3, // and PS_MS
+		s.cc.PS_CANC,
+		s.cc.PS_THDL,
+		s.cc.PS_THDH,
+	}
+}
+
+// cacheable returns true if the given command code is held in the register
+// cache
+func (s *Sensor) cacheable(commandCode byte) bool {
+
+	if !s.cache {
+		return false
+	}
+
+	for _, c := range s.configRegisters() {
+		if c == commandCode {
+			return true
+		}
+	}
+
+	return false
+}
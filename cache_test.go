@@ -0,0 +1,147 @@
+package vcnl40xx_test
+
+import (
+	"testing"
+
+	vcnl "github.com/swdee/go-vcnl40xx"
+	"github.com/swdee/go-vcnl40xx/sim"
+)
+
+// countTransactions returns the number of read and write transactions
+// received by the device
+func countTransactions(dev *sim.Device) (reads, writes int) {
+
+	for _, t := range dev.Transactions() {
+		if t.Write {
+			writes++
+		} else {
+			reads++
+		}
+	}
+
+	return reads, writes
+}
+
+func TestRegisterCacheTransactions(t *testing.T) {
+
+	tests := []struct {
+		name       string
+		cache      bool
+		wantReads  int
+		wantWrites int
+	}{
+		// every read-modify-write reads the register from the sensor, once
+		// for the field and once more to keep the other byte
+		{"disabled", false, 6, 3},
+		// only the first read of the register goes to the sensor
+		{"enabled", true, 1, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			s, dev := connect(t, vcnl.VCNL4040)
+
+			if tt.cache {
+				s.EnableRegisterCache()
+			}
+
+			dev.ClearTransactions()
+
+			steps := []func() error{
+				func() error { return s.SetIRDutyCycle(160) },
+				func() error { return s.SetProximityInterruptType(vcnl.InterruptBoth) },
+				s.PowerOnProximity,
+			}
+
+			for _, step := range steps {
+				if err := step(); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}
+
+			reads, writes := countTransactions(dev)
+
+			if reads != tt.wantReads || writes != tt.wantWrites {
+				t.Errorf("got %d reads and %d writes, want %d and %d",
+					reads, writes, tt.wantReads, tt.wantWrites)
+			}
+		})
+	}
+}
+
+func TestRefresh(t *testing.T) {
+
+	s, dev := connect(t, vcnl.VCNL4040)
+	cc := vcnl.CommandCodes4040()
+
+	dev.ClearTransactions()
+
+	if err := s.Refresh(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if n := len(dev.Transactions()); n != 0 {
+		t.Errorf("refresh with the cache disabled made %d transactions", n)
+	}
+
+	s.EnableRegisterCache()
+
+	if err := s.SetProximityHighThreshold(1000); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// changed behind the driver's back
+	dev.SetRegister(cc.PS_THDH, 2000)
+
+	dev.ClearTransactions()
+
+	if err := s.Refresh(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if reads, writes := countTransactions(dev); reads != 8 || writes != 0 {
+		t.Errorf("got %d reads and %d writes, want 8 and 0", reads, writes)
+	}
+
+	if got, _ := s.GetProximityHighThreshold(); got != 2000 {
+		t.Errorf("threshold is %d after refresh, want 2000", got)
+	}
+}
+
+func TestSync(t *testing.T) {
+
+	s, dev := connect(t, vcnl.VCNL4040)
+	cc := vcnl.CommandCodes4040()
+
+	if err := s.SetProximityHighThreshold(1000); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	dev.Reset()
+	dev.ClearTransactions()
+
+	if err := s.Sync(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if n := len(dev.Transactions()); n != 0 {
+		t.Errorf("sync with the cache disabled made %d transactions", n)
+	}
+
+	s.EnableRegisterCache()
+
+	if err := s.SetProximityHighThreshold(1000); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	dev.Reset()
+
+	if err := s.Sync(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := dev.Register(cc.PS_THDH); got != 1000 {
+		t.Errorf("threshold is %d after sync, want 1000", got)
+	}
+}
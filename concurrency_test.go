@@ -0,0 +1,144 @@
+package vcnl40xx_test
+
+import (
+	"sync"
+	"testing"
+
+	vcnl "github.com/swdee/go-vcnl40xx"
+)
+
+func TestConcurrentUse(t *testing.T) {
+
+	for _, cache := range []bool{false, true} {
+		name := "uncached"
+
+		if cache {
+			name = "cached"
+		}
+
+		t.Run(name, func(t *testing.T) {
+
+			const rounds = 200
+
+			s, dev := connect(t, vcnl.VCNL4040)
+
+			if cache {
+				s.EnableRegisterCache()
+			}
+
+			dev.SetProximity(1500)
+
+			// each setter alternates its field and finishes on a known value,
+			// fields sharing a register are updated from different goroutines
+			// so a lost read-modify-write leaves a stale field behind
+			workers := []func(i int) error{
+				func(i int) error {
+					_, err := s.GetProximity()
+					return err
+				},
+				func(i int) error {
+					return s.SetProximityHighThreshold(uint16(i))
+				},
+				func(i int) error {
+					// PS_CONF1
+					if i%2 == 0 {
+						return s.SetIRDutyCycle(40)
+					}
+					return s.SetIRDutyCycle(320)
+				},
+				func(i int) error {
+					// PS_CONF2
+					if i%2 == 0 {
+						return s.SetProximityInterruptType(vcnl.InterruptClose)
+					}
+					return s.SetProximityInterruptType(vcnl.InterruptBoth)
+				},
+				func(i int) error {
+					// PS_CONF3
+					if i%2 == 0 {
+						return s.DisableSmartPersistence()
+					}
+					return s.EnableSmartPersistance()
+				},
+				func(i int) error {
+					// PS_MS
+					if i%2 == 0 {
+						return s.SetLEDCurrent(50)
+					}
+					return s.SetLEDCurrent(200)
+				},
+			}
+
+			var wg sync.WaitGroup
+			errs := make(chan error, len(workers))
+
+			for _, work := range workers {
+				wg.Add(1)
+
+				go func(work func(i int) error) {
+
+					defer wg.Done()
+
+					for i := 0; i < rounds; i++ {
+						if err := work(i); err != nil {
+							errs <- err
+							return
+						}
+					}
+				}(work)
+			}
+
+			wg.Wait()
+			close(errs)
+
+			for err := range errs {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			cc := vcnl.CommandCodes4040()
+			r := vcnl.Registers4040()
+
+			conf12 := dev.Register(cc.PS_CONF1)
+			conf3ms := dev.Register(cc.PS_CONF3)
+
+			checks := []struct {
+				name string
+				got  uint8
+				want uint8
+			}{
+				{"PS_DUTY", uint8(conf12) &^ r.PS_DUTY_MASK, r.PS_DUTY_320},
+				{"PS_INT", uint8(conf12>>8) &^ r.PS_INT_MASK, r.PS_INT_BOTH},
+				{"PS_SMART_PERS", uint8(conf3ms) &^ r.PS_SMART_PERS_MASK, r.PS_SMART_PERS_ENABLE},
+				{"LED_I", uint8(conf3ms>>8) &^ r.LED_I_MASK, r.LED_200MA},
+			}
+
+			for _, c := range checks {
+				if c.got != c.want {
+					t.Errorf("%s field is 0x%02X, want 0x%02X", c.name, c.got, c.want)
+				}
+			}
+
+			if got := dev.Register(cc.PS_THDH); got != rounds-1 {
+				t.Errorf("PS_THDH is %d, want %d", got, rounds-1)
+			}
+
+			// writing the cache back must not change the device if the cache
+			// agrees with it after the concurrent updates
+			if cache {
+				want := configRegisters(dev, vcnl.VCNL4040)
+
+				if err := s.Sync(); err != nil {
+					t.Fatalf("error syncing cache: %v", err)
+				}
+
+				got := configRegisters(dev, vcnl.VCNL4040)
+
+				for cmd, value := range want {
+					if got[cmd] != value {
+						t.Errorf("command code 0x%02X cached as 0x%04X, device has 0x%04X", cmd, got[cmd], value)
+					}
+				}
+			}
+		})
+	}
+}
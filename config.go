@@ -0,0 +1,502 @@
+package vcnl40xx
+
+import (
+	"context"
+	"fmt"
+)
+
+// Config defines the complete configuration of the sensor which can be
+// written with Apply and read back with ReadConfig
+type Config struct {
+	// LEDCurrent is the IR LED sink current in mA. valid values are 50, 75,
+	// 100, 120, 140, 160, 180, or 200
+	LEDCurrent LEDCurrent
+	// IRDutyCycle is the IR LED duty ratio as 1/IRDutyCycle. valid values are
+	// 40, 80, 160, or 320
+	IRDutyCycle DutyCycle
+	// ProximityIntegrationTime in T. valid values are 1, 2, 3, 4, or 8.
+	// It is only used by Apply if ProximityIT is not set and is never set by
+	// ReadConfig.
+	//
+	// Deprecated: use ProximityIT.
+	ProximityIntegrationTime uint8
+	// ProximityIT is the proximity integration time, including the 1.5T, 2.5T
+	// and 3.5T half steps.  If ProximityIntegrationTime is also set they must
+	// be the same time.
+	ProximityIT ProximityIT
+	// ProximityResolution in bits. valid values are 12 or 16
+	ProximityResolution uint8
+	// ProximityPersistance is the number of consecutive hits needed to
+	// trigger a proximity interrupt
+	ProximityPersistance ProximityPersistance
+	// ProximityInterrupt is the proximity interrupt type
+	ProximityInterrupt InterruptType
+	// SmartPersistance enables proximity smart persistence
+	SmartPersistance bool
+	// ActiveForceMode enables proximity active force mode
+	ActiveForceMode bool
+	// ProximityLogicMode enables the proximity detection logic output mode
+	ProximityLogicMode bool
+	// ProximityHighThreshold is the proximity interrupt high threshold
+	ProximityHighThreshold uint16
+	// ProximityLowThreshold is the proximity interrupt low threshold
+	ProximityLowThreshold uint16
+	// ProximityCancellation is the proximity cancellation level
+	ProximityCancellation uint16
+	// ProximityEnabled powers on the proximity sensor
+	ProximityEnabled bool
+	// ProximityMultiPulse is the number of IR LED pulses per measurement.
+	// VCNL4040 only
+	ProximityMultiPulse ProximityMultiPulse
+	// ProximityGain is the proximity gain mode. VCNL4030 and VCNL4035 only
+	ProximityGain ProximityGain
+	// ProximityTwoStepRatio is the sensitivity of two-step gain mode.
+	// VCNL4030 and VCNL4035 only
+	ProximityTwoStepRatio ProximityTwoStepRatio
+	// LowLEDCurrent reduces the IR LED current to 1/10. VCNL4030 and
+	// VCNL4035 only
+	LowLEDCurrent bool
+	// SunlightCancellation enables proximity sunlight cancellation
+	SunlightCancellation bool
+	// SunlightCurrent is the sunlight cancellation current. VCNL4030 and
+	// VCNL4035 only
+	SunlightCurrent SunlightCurrent
+	// SunlightProtection is the sunlight protection capability. VCNL4030
+	// and VCNL4035 only
+	SunlightProtection SunlightProtection
+	// SunlightOutput is the proximity output in sunlight protection mode.
+	// VCNL4030 and VCNL4035 only
+	SunlightOutput SunlightOutput
+
+	// AmbientIntegrationTime in milliseconds. valid values for VCNL4040 are
+	// 80, 160, 320, or 640. for VCNL4030 and VCNL4035 are 50, 100, 200, 400,
+	// or 800
+	AmbientIntegrationTime ALSIntegrationTime
+	// AmbientPersistance is the number of consecutive hits needed to trigger
+	// an ambient light interrupt
+	AmbientPersistance AmbientPersistance
+	// AmbientInterrupts enables ambient light interrupts
+	AmbientInterrupts bool
+	// AmbientHighThreshold is the ambient light interrupt high threshold
+	AmbientHighThreshold uint16
+	// AmbientLowThreshold is the ambient light interrupt low threshold
+	AmbientLowThreshold uint16
+	// AmbientEnabled powers on the ambient light sensor
+	AmbientEnabled bool
+	// AmbientHighDynamicRange is the ALS_HD dynamic range. VCNL4030 and
+	// VCNL4035 only
+	AmbientHighDynamicRange AmbientRange
+	// AmbientSensitivityRange is the ALS_NS dynamic range. VCNL4030 and
+	// VCNL4035 only
+	AmbientSensitivityRange AmbientRange
+
+	// WhiteChannel enables the white light channel
+	WhiteChannel bool
+}
+
+// configCheck is a setting value checked by Validate
+type configCheck struct {
+	name    string
+	options []option
+	value   uint16
+}
+
+// configField is a register field decoded by ReadConfig
+type configField struct {
+	name     string
+	options  []option
+	mask     uint8
+	contents uint8
+	set      func(v uint16)
+}
+
+// DefaultConfig returns the configuration of a sensor initialised with Init
+// from its power on state
+func DefaultConfig(m Model) Config {
+
+	cfg := Config{
+		LEDCurrent:             200,
+		IRDutyCycle:            40,
+		ProximityIT:            ProximityIT8T,
+		ProximityResolution:    16,
+		ProximityPersistance:   ProximityPersistance1,
+		ProximityInterrupt:     InterruptDisable,
+		SmartPersistance:       true,
+		ProximityEnabled:       true,
+		AmbientIntegrationTime: 80,
+		AmbientPersistance:     AmbientPersistance1,
+		AmbientEnabled:         true,
+		WhiteChannel:           true,
+	}
+
+	if m == VCNL4040 {
+		cfg.ProximityMultiPulse = ProximityMultiPulse1
+	}
+
+	if m == VCNL4030 || m == VCNL4035 {
+		cfg.AmbientIntegrationTime = 50
+		cfg.ProximityGain = ProximityGainTwoStep
+		cfg.ProximityTwoStepRatio = ProximityTwoStep4
+		cfg.AmbientHighDynamicRange = AmbientRange1
+		cfg.AmbientSensitivityRange = AmbientRange1
+		cfg.SunlightCurrent = SunlightCurrent1
+		cfg.SunlightProtection = SunlightProtection1
+		cfg.SunlightOutput = SunlightOutput00
+	}
+
+	return cfg
+}
+
+// Validate checks all settings of the configuration are supported by the
+// given sensor Model.  Unlike the individual setters no value is rounded.
+// Settings only available on other models must be left as their zero value.
+func (c Config) Validate(m Model) error {
+
+	_, reg, err := modelTables(m)
+
+	if err != nil {
+		return err
+	}
+
+	checks := []configCheck{
+		{"LED current", ledCurrentOptions(reg), uint16(c.LEDCurrent)},
+		{"IR duty cycle", irDutyOptions(reg), uint16(c.IRDutyCycle)},
+		{"proximity resolution", proximityResolutionOptions(reg), uint16(c.ProximityResolution)},
+		{"proximity persistance", proximityPersistanceOptions(reg), uint16(c.ProximityPersistance)},
+		{"proximity interrupt type", interruptTypeOptions(reg), uint16(c.ProximityInterrupt)},
+		{"ambient integration time", ambientITOptions(m, reg), uint16(c.AmbientIntegrationTime)},
+		{"ambient persistance", ambientPersistanceOptions(reg), uint16(c.AmbientPersistance)},
+	}
+
+	if c.ProximityIT == 0 {
+		checks = append(checks, configCheck{
+			"proximity integration time", proximityITWholeOptions(reg), uint16(c.ProximityIntegrationTime),
+		})
+
+	} else if c.ProximityIntegrationTime != 0 && c.proximityIT() != 2*uint16(c.ProximityIntegrationTime) {
+		return fmt.Errorf("proximity integration time %dT does not match ProximityIT setting: %w",
+			c.ProximityIntegrationTime, ErrInvalidArgument)
+
+	} else {
+		checks = append(checks, configCheck{
+			"proximity integration time", proximityITOptions(reg), c.proximityIT(),
+		})
+	}
+
+	if m == VCNL4040 {
+		checks = append(checks, configCheck{
+			"proximity multi pulse", proximityMultiPulseOptions(reg), uint16(c.ProximityMultiPulse),
+		})
+
+	} else if c.ProximityMultiPulse != 0 {
+		return fmt.Errorf("proximity multi pulse: %w", ErrUnsupportedFeature)
+	}
+
+	if m == VCNL4030 || m == VCNL4035 {
+		checks = append(checks, []configCheck{
+			{"proximity gain", proximityGainOptions(reg), uint16(c.ProximityGain)},
+			{"proximity two-step ratio", proximityTwoStepOptions(reg), uint16(c.ProximityTwoStepRatio)},
+			{"ambient high dynamic range", ambientHDOptions(reg), uint16(c.AmbientHighDynamicRange)},
+			{"ambient sensitivity range", ambientNSOptions(reg), uint16(c.AmbientSensitivityRange)},
+			{"sunlight cancellation current", sunlightCurrentOptions(reg), uint16(c.SunlightCurrent)},
+			{"sunlight protection", sunlightProtectionOptions(reg), uint16(c.SunlightProtection)},
+			{"sunlight protection output", sunlightOutputOptions(reg), uint16(c.SunlightOutput)},
+		}...)
+
+	} else if c.ProximityGain != 0 || c.ProximityTwoStepRatio != 0 || c.LowLEDCurrent {
+		return fmt.Errorf("proximity gain settings: %w", ErrUnsupportedFeature)
+
+	} else if c.AmbientHighDynamicRange != 0 || c.AmbientSensitivityRange != 0 {
+		return fmt.Errorf("ambient range settings: %w", ErrUnsupportedFeature)
+
+	} else if c.SunlightCurrent != 0 || c.SunlightProtection != 0 || c.SunlightOutput != 0 {
+		return fmt.Errorf("sunlight protection settings: %w", ErrUnsupportedFeature)
+	}
+
+	for _, chk := range checks {
+		if _, err := exactOption(chk.name, chk.options, chk.value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// proximityIT returns the proximity integration time in half T units from
+// ProximityIT, or ProximityIntegrationTime if it is not set
+func (c Config) proximityIT() uint16 {
+
+	if c.ProximityIT != 0 {
+		return uint16(c.ProximityIT)
+	}
+
+	return 2 * uint16(c.ProximityIntegrationTime)
+}
+
+// Apply validates and writes the configuration to the sensor.  Each register
+// is written at most once and only if its contents change.  Auto-ranging is
+// turned off if the configuration selects a different ambient range to the
+// one it is using.
+func (s *Sensor) Apply(cfg Config) error {
+	return s.ApplyContext(context.Background(), cfg)
+}
+
+// ApplyContext is Apply returning the context error if ctx is done before the
+// configuration is written
+func (s *Sensor) ApplyContext(ctx context.Context, cfg Config) error {
+
+	if err := cfg.Validate(s.model); err != nil {
+		return err
+	}
+
+	s.leaveAutoRange(alsRange{uint16(cfg.AmbientIntegrationTime),
+		cfg.AmbientHighDynamicRange, cfg.AmbientSensitivityRange})
+
+	// hold the lock so other goroutines can not change registers between
+	// reading the current contents and writing the new configuration
+	if err := s.mu.LockContext(ctx); err != nil {
+		return err
+	}
+
+	defer s.mu.Unlock()
+
+	current := make(map[byte]uint16)
+
+	for _, commandCode := range s.configRegisters() {
+
+		value, err := s.readRegister(ctx, commandCode)
+
+		if err != nil {
+			return err
+		}
+
+		current[commandCode] = value
+	}
+
+	pick := func(options []option, value uint16) uint8 {
+		bits, _ := lookup(options, value)
+		return bits
+	}
+
+	// ALS_CONF and ALS_CONF2
+	als := current[s.cc.ALS_CONF]
+	als = maskLower(als, s.reg.ALS_IT_MASK, pick(ambientITOptions(s.model, s.reg), uint16(cfg.AmbientIntegrationTime)))
+	als = maskLower(als, s.reg.ALS_PERS_MASK, pick(ambientPersistanceOptions(s.reg), uint16(cfg.AmbientPersistance)))
+	als = maskLower(als, s.reg.ALS_INT_EN_MASK, choose(cfg.AmbientInterrupts, s.reg.ALS_INT_ENABLE, s.reg.ALS_INT_DISABLE))
+	als = maskLower(als, s.reg.ALS_SD_MASK, choose(cfg.AmbientEnabled, s.reg.ALS_SD_POWER_ON, s.reg.ALS_SD_POWER_OFF))
+
+	if s.model == VCNL4030 || s.model == VCNL4035 {
+		als = maskLower(als, s.reg.ALS_HD_MASK, pick(ambientHDOptions(s.reg), uint16(cfg.AmbientHighDynamicRange)))
+		als = maskUpper(als, s.reg.ALS_NS_MASK, pick(ambientNSOptions(s.reg), uint16(cfg.AmbientSensitivityRange)))
+		als = maskUpper(als, s.reg.WHITE_SD_MASK, choose(cfg.WhiteChannel, s.reg.WHITE_SD_POWER_ON, s.reg.WHITE_SD_POWER_OFF))
+	}
+
+	// PS_CONF1 and PS_CONF2
+	ps12 := current[s.cc.PS_CONF1]
+	ps12 = maskLower(ps12, s.reg.PS_DUTY_MASK, pick(irDutyOptions(s.reg), uint16(cfg.IRDutyCycle)))
+	ps12 = maskLower(ps12, s.reg.PS_PERS_MASK, pick(proximityPersistanceOptions(s.reg), uint16(cfg.ProximityPersistance)))
+	ps12 = maskLower(ps12, s.reg.PS_IT_MASK, pick(proximityITOptions(s.reg), cfg.proximityIT()))
+	ps12 = maskLower(ps12, s.reg.PS_SD_MASK, choose(cfg.ProximityEnabled, s.reg.PS_SD_POWER_ON, s.reg.PS_SD_POWER_OFF))
+	ps12 = maskUpper(ps12, s.reg.PS_HD_MASK, pick(proximityResolutionOptions(s.reg), uint16(cfg.ProximityResolution)))
+	ps12 = maskUpper(ps12, s.reg.PS_INT_MASK, pick(interruptTypeOptions(s.reg), uint16(cfg.ProximityInterrupt)))
+
+	if s.model == VCNL4030 || s.model == VCNL4035 {
+		ps12 = maskUpper(ps12, s.reg.PS_GAIN_MASK, pick(proximityGainOptions(s.reg), uint16(cfg.ProximityGain)))
+		ps12 = maskUpper(ps12, s.reg.PS_NS_MASK, pick(proximityTwoStepOptions(s.reg), uint16(cfg.ProximityTwoStepRatio)))
+	}
+
+	// PS_CONF3 and PS_MS
+	ps3 := current[s.cc.PS_CONF3]
+	ps3 = maskLower(ps3, s.reg.PS_SMART_PERS_MASK, choose(cfg.SmartPersistance, s.reg.PS_SMART_PERS_ENABLE, s.reg.PS_SMART_PERS_DISABLE))
+	ps3 = maskLower(ps3, s.reg.PS_AF_MASK, choose(cfg.ActiveForceMode, s.reg.PS_AF_ENABLE, s.reg.PS_AF_DISABLE))
+	ps3 = maskUpper(ps3, s.reg.WHITE_EN_MASK, choose(cfg.WhiteChannel, s.reg.WHITE_ENABLE, s.reg.WHITE_DISABLE))
+	ps3 = maskLower(ps3, s.reg.PS_SC_EN_MASK, choose(cfg.SunlightCancellation, s.reg.PS_SC_EN_ENABLE, s.reg.PS_SC_EN_DISABLE))
+	ps3 = maskUpper(ps3, s.reg.LED_I_MASK, pick(ledCurrentOptions(s.reg), uint16(cfg.LEDCurrent)))
+
+	if s.model == VCNL4030 || s.model == VCNL4035 {
+		ps3 = maskLower(ps3, s.reg.LED_I_LOW_MASK, choose(cfg.LowLEDCurrent, s.reg.LED_I_LOW_ENABLE, s.reg.LED_I_LOW_DISABLE))
+		ps3 = maskLower(ps3, s.reg.CONF3_PS_MS_MASK, choose(cfg.ProximityLogicMode, s.reg.CONF3_PS_MS_OUTPUT_MODE, s.reg.CONF3_PS_MS_NORMAL))
+		ps3 = maskUpper(ps3, s.reg.PS_SC_CUR_MASK, pick(sunlightCurrentOptions(s.reg), uint16(cfg.SunlightCurrent)))
+		ps3 = maskUpper(ps3, s.reg.PS_SP_MASK, pick(sunlightProtectionOptions(s.reg), uint16(cfg.SunlightProtection)))
+		ps3 = maskUpper(ps3, s.reg.PS_SPO_MASK, pick(sunlightOutputOptions(s.reg), uint16(cfg.SunlightOutput)))
+	} else {
+		ps3 = maskLower(ps3, s.reg.PS_MPS_MASK, pick(proximityMultiPulseOptions(s.reg), uint16(cfg.ProximityMultiPulse)))
+		ps3 = maskUpper(ps3, s.reg.PS_MS_MASK, choose(cfg.ProximityLogicMode, s.reg.PS_MS_ENABLE, s.reg.PS_MS_DISABLE))
+	}
+
+	// write thresholds before the sensors are powered on
+	writes := []struct {
+		commandCode byte
+		value       uint16
+	}{
+		{s.cc.PS_CONF3, ps3},
+		{s.cc.PS_CANC, cfg.ProximityCancellation},
+		{s.cc.PS_THDL, cfg.ProximityLowThreshold},
+		{s.cc.PS_THDH, cfg.ProximityHighThreshold},
+		{s.cc.ALS_THDL, cfg.AmbientLowThreshold},
+		{s.cc.ALS_THDH, cfg.AmbientHighThreshold},
+		{s.cc.PS_CONF1, ps12},
+		{s.cc.ALS_CONF, als},
+	}
+
+	for _, w := range writes {
+
+		if w.value == current[w.commandCode] {
+			continue
+		}
+
+		if err := s.writeRegister(ctx, w.commandCode, w.value); err != nil {
+			return fmt.Errorf("error writing command code 0x%02X: %w", w.commandCode, err)
+		}
+	}
+
+	return nil
+}
+
+// ReadConfig reads the configuration registers from the sensor and decodes
+// them into a Config
+func (s *Sensor) ReadConfig() (Config, error) {
+	return s.ReadConfigContext(context.Background())
+}
+
+// ReadConfigContext is ReadConfig returning the context error if ctx is done
+// before the registers are read
+func (s *Sensor) ReadConfigContext(ctx context.Context) (Config, error) {
+
+	var cfg Config
+
+	regs := make(map[byte]uint16)
+
+	if err := s.mu.LockContext(ctx); err != nil {
+		return cfg, err
+	}
+
+	for _, commandCode := range s.configRegisters() {
+
+		value, err := s.readRegister(ctx, commandCode)
+
+		if err != nil {
+			s.mu.Unlock()
+			return cfg, err
+		}
+
+		regs[commandCode] = value
+	}
+
+	s.mu.Unlock()
+
+	alsLower := byte(regs[s.cc.ALS_CONF] & 0xFF)
+	alsUpper := byte(regs[s.cc.ALS_CONF] >> 8)
+	ps1 := byte(regs[s.cc.PS_CONF1] & 0xFF)
+	ps2 := byte(regs[s.cc.PS_CONF1] >> 8)
+	ps3 := byte(regs[s.cc.PS_CONF3] & 0xFF)
+	psMS := byte(regs[s.cc.PS_CONF3] >> 8)
+
+	fields := []configField{
+		{"LED current", ledCurrentOptions(s.reg), s.reg.LED_I_MASK, psMS,
+			func(v uint16) { cfg.LEDCurrent = LEDCurrent(v) }},
+		{"IR duty cycle", irDutyOptions(s.reg), s.reg.PS_DUTY_MASK, ps1,
+			func(v uint16) { cfg.IRDutyCycle = DutyCycle(v) }},
+		{"proximity integration time", proximityITOptions(s.reg), s.reg.PS_IT_MASK, ps1,
+			func(v uint16) { cfg.ProximityIT = ProximityIT(v) }},
+		{"proximity resolution", proximityResolutionOptions(s.reg), s.reg.PS_HD_MASK, ps2,
+			func(v uint16) { cfg.ProximityResolution = uint8(v) }},
+		{"proximity persistance", proximityPersistanceOptions(s.reg), s.reg.PS_PERS_MASK, ps1,
+			func(v uint16) { cfg.ProximityPersistance = ProximityPersistance(v) }},
+		{"proximity interrupt type", interruptTypeOptions(s.reg), s.reg.PS_INT_MASK, ps2,
+			func(v uint16) { cfg.ProximityInterrupt = InterruptType(v) }},
+		{"ambient integration time", ambientITOptions(s.model, s.reg), s.reg.ALS_IT_MASK, alsLower,
+			func(v uint16) { cfg.AmbientIntegrationTime = ALSIntegrationTime(v) }},
+		{"ambient persistance", ambientPersistanceOptions(s.reg), s.reg.ALS_PERS_MASK, alsLower,
+			func(v uint16) { cfg.AmbientPersistance = AmbientPersistance(v) }},
+	}
+
+	if s.model == VCNL4030 || s.model == VCNL4035 {
+		fields = append(fields, []configField{
+			{"proximity gain", proximityGainOptions(s.reg), s.reg.PS_GAIN_MASK, ps2,
+				func(v uint16) { cfg.ProximityGain = ProximityGain(v) }},
+			{"proximity two-step ratio", proximityTwoStepOptions(s.reg), s.reg.PS_NS_MASK, ps2,
+				func(v uint16) { cfg.ProximityTwoStepRatio = ProximityTwoStepRatio(v) }},
+			{"ambient high dynamic range", ambientHDOptions(s.reg), s.reg.ALS_HD_MASK, alsLower,
+				func(v uint16) { cfg.AmbientHighDynamicRange = AmbientRange(v) }},
+			{"ambient sensitivity range", ambientNSOptions(s.reg), s.reg.ALS_NS_MASK, alsUpper,
+				func(v uint16) { cfg.AmbientSensitivityRange = AmbientRange(v) }},
+			{"sunlight cancellation current", sunlightCurrentOptions(s.reg), s.reg.PS_SC_CUR_MASK, psMS,
+				func(v uint16) { cfg.SunlightCurrent = SunlightCurrent(v) }},
+			{"sunlight protection", sunlightProtectionOptions(s.reg), s.reg.PS_SP_MASK, psMS,
+				func(v uint16) { cfg.SunlightProtection = SunlightProtection(v) }},
+			{"sunlight protection output", sunlightOutputOptions(s.reg), s.reg.PS_SPO_MASK, psMS,
+				func(v uint16) { cfg.SunlightOutput = SunlightOutput(v) }},
+		}...)
+
+		cfg.LowLEDCurrent = ps3&^s.reg.LED_I_LOW_MASK == s.reg.LED_I_LOW_ENABLE
+		cfg.ProximityLogicMode = ps3&^s.reg.CONF3_PS_MS_MASK == s.reg.CONF3_PS_MS_OUTPUT_MODE
+	} else {
+		fields = append(fields, configField{
+			"proximity multi pulse", proximityMultiPulseOptions(s.reg), s.reg.PS_MPS_MASK, ps3,
+			func(v uint16) { cfg.ProximityMultiPulse = ProximityMultiPulse(v) },
+		})
+
+		cfg.ProximityLogicMode = psMS&^s.reg.PS_MS_MASK == s.reg.PS_MS_ENABLE
+	}
+
+	for _, f := range fields {
+
+		v, ok := decode(f.options, f.mask, f.contents)
+
+		if !ok {
+			return cfg, fmt.Errorf("unrecognised %s register value 0x%02X", f.name, f.contents&^f.mask)
+		}
+
+		f.set(v)
+	}
+
+	cfg.SmartPersistance = ps3&^s.reg.PS_SMART_PERS_MASK == s.reg.PS_SMART_PERS_ENABLE
+	cfg.ActiveForceMode = ps3&^s.reg.PS_AF_MASK == s.reg.PS_AF_ENABLE
+	cfg.SunlightCancellation = ps3&^s.reg.PS_SC_EN_MASK == s.reg.PS_SC_EN_ENABLE
+	cfg.ProximityEnabled = ps1&^s.reg.PS_SD_MASK == s.reg.PS_SD_POWER_ON
+	cfg.ProximityHighThreshold = regs[s.cc.PS_THDH]
+	cfg.ProximityLowThreshold = regs[s.cc.PS_THDL]
+	cfg.ProximityCancellation = regs[s.cc.PS_CANC]
+
+	cfg.AmbientInterrupts = alsLower&^s.reg.ALS_INT_EN_MASK == s.reg.ALS_INT_ENABLE
+	cfg.AmbientEnabled = alsLower&^s.reg.ALS_SD_MASK == s.reg.ALS_SD_POWER_ON
+	cfg.AmbientHighThreshold = regs[s.cc.ALS_THDH]
+	cfg.AmbientLowThreshold = regs[s.cc.ALS_THDL]
+
+	cfg.WhiteChannel = psMS&^s.reg.WHITE_EN_MASK == s.reg.WHITE_ENABLE
+
+	if s.model == VCNL4030 || s.model == VCNL4035 {
+		cfg.WhiteChannel = cfg.WhiteChannel &&
+			alsUpper&^s.reg.WHITE_SD_MASK == s.reg.WHITE_SD_POWER_ON
+	}
+
+	return cfg, nil
+}
+
+// choose returns the on register bits if enabled is true, otherwise the off
+// bits
+func choose(enabled bool, on uint8, off uint8) uint8 {
+
+	if enabled {
+		return on
+	}
+
+	return off
+}
+
+// maskLower masks the given bits into the lower byte of a register value
+func maskLower(value uint16, mask byte, bits byte) uint16 {
+
+	lower := byte(value&0xFF)&mask | bits
+
+	return value&0xFF00 | uint16(lower)
+}
+
+// maskUpper masks the given bits into the upper byte of a register value
+func maskUpper(value uint16, mask byte, bits byte) uint16 {
+
+	upper := byte(value>>8)&mask | bits
+
+	return value&0x00FF | uint16(upper)<<8
+}
@@ -0,0 +1,258 @@
+package vcnl40xx_test
+
+import (
+	"errors"
+	"testing"
+
+	vcnl "github.com/swdee/go-vcnl40xx"
+)
+
+func TestApplyWritesChangedRegisters(t *testing.T) {
+
+	cc := vcnl.CommandCodes4040()
+
+	tests := []struct {
+		name   string
+		change func(cfg *vcnl.Config)
+		want   []byte
+	}{
+		{"unchanged", func(cfg *vcnl.Config) {}, nil},
+		{"threshold", func(cfg *vcnl.Config) { cfg.ProximityHighThreshold = 3000 }, []byte{cc.PS_THDH}},
+		{"LED current", func(cfg *vcnl.Config) { cfg.LEDCurrent = vcnl.LED100mA }, []byte{cc.PS_CONF3}},
+		{"two fields in one register", func(cfg *vcnl.Config) {
+			cfg.IRDutyCycle = vcnl.Duty320
+			cfg.ProximityInterrupt = vcnl.InterruptBoth
+		}, []byte{cc.PS_CONF1}},
+		{"several registers", func(cfg *vcnl.Config) {
+			cfg.AmbientInterrupts = true
+			cfg.AmbientLowThreshold = 10
+			cfg.ProximityCancellation = 5
+		}, []byte{cc.PS_CANC, cc.ALS_THDL, cc.ALS_CONF}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			s, dev := connect(t, vcnl.VCNL4040)
+			cfg := vcnl.DefaultConfig(vcnl.VCNL4040)
+
+			if err := s.Apply(cfg); err != nil {
+				t.Fatalf("error applying config: %v", err)
+			}
+
+			dev.ClearTransactions()
+			tt.change(&cfg)
+
+			if err := s.Apply(cfg); err != nil {
+				t.Fatalf("error applying config: %v", err)
+			}
+
+			writes := dev.Writes()
+
+			if len(writes) != len(tt.want) {
+				t.Fatalf("got %d writes %+v, want %d", len(writes), writes, len(tt.want))
+			}
+
+			for i, cmd := range tt.want {
+				if writes[i].Cmd != cmd {
+					t.Errorf("write %d is to command code 0x%02X, want 0x%02X", i, writes[i].Cmd, cmd)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateRejectsInvalidFields(t *testing.T) {
+
+	tests := []struct {
+		name   string
+		model  vcnl.Model
+		change func(cfg *vcnl.Config)
+		want   error
+	}{
+		{"LEDCurrent", vcnl.VCNL4040, func(c *vcnl.Config) { c.LEDCurrent = 130 }, vcnl.ErrInvalidArgument},
+		{"IRDutyCycle", vcnl.VCNL4040, func(c *vcnl.Config) { c.IRDutyCycle = 100 }, vcnl.ErrInvalidArgument},
+		{"ProximityIntegrationTime", vcnl.VCNL4040, func(c *vcnl.Config) {
+			c.ProximityIntegrationTime = 5
+		}, vcnl.ErrInvalidArgument},
+		{"ProximityIT", vcnl.VCNL4040, func(c *vcnl.Config) { c.ProximityIT = 9 }, vcnl.ErrInvalidArgument},
+		{"ProximityResolution", vcnl.VCNL4040, func(c *vcnl.Config) { c.ProximityResolution = 14 }, vcnl.ErrInvalidArgument},
+		{"ProximityPersistance", vcnl.VCNL4040, func(c *vcnl.Config) { c.ProximityPersistance = 5 }, vcnl.ErrInvalidArgument},
+		{"ProximityInterrupt", vcnl.VCNL4040, func(c *vcnl.Config) { c.ProximityInterrupt = 0 }, vcnl.ErrInvalidArgument},
+		{"AmbientIntegrationTime", vcnl.VCNL4040, func(c *vcnl.Config) {
+			c.AmbientIntegrationTime = vcnl.ALSIT50ms
+		}, vcnl.ErrInvalidArgument},
+		{"AmbientPersistance", vcnl.VCNL4040, func(c *vcnl.Config) { c.AmbientPersistance = 3 }, vcnl.ErrInvalidArgument},
+		{"ProximityMultiPulse", vcnl.VCNL4040, func(c *vcnl.Config) { c.ProximityMultiPulse = 3 }, vcnl.ErrInvalidArgument},
+		{"ProximityGain", vcnl.VCNL4030, func(c *vcnl.Config) { c.ProximityGain = 0 }, vcnl.ErrInvalidArgument},
+		{"ProximityTwoStepRatio", vcnl.VCNL4030, func(c *vcnl.Config) {
+			c.ProximityTwoStepRatio = 2
+		}, vcnl.ErrInvalidArgument},
+		{"AmbientHighDynamicRange", vcnl.VCNL4030, func(c *vcnl.Config) {
+			c.AmbientHighDynamicRange = 3
+		}, vcnl.ErrInvalidArgument},
+		{"AmbientSensitivityRange", vcnl.VCNL4030, func(c *vcnl.Config) {
+			c.AmbientSensitivityRange = 0
+		}, vcnl.ErrInvalidArgument},
+		{"SunlightCurrent", vcnl.VCNL4035, func(c *vcnl.Config) { c.SunlightCurrent = 3 }, vcnl.ErrInvalidArgument},
+		{"SunlightProtection", vcnl.VCNL4035, func(c *vcnl.Config) { c.SunlightProtection = 0 }, vcnl.ErrInvalidArgument},
+		{"SunlightOutput", vcnl.VCNL4035, func(c *vcnl.Config) { c.SunlightOutput = 3 }, vcnl.ErrInvalidArgument},
+		{"ProximityMultiPulse on VCNL4030", vcnl.VCNL4030, func(c *vcnl.Config) {
+			c.ProximityMultiPulse = vcnl.ProximityMultiPulse2
+		}, vcnl.ErrUnsupportedFeature},
+		{"ProximityGain on VCNL4040", vcnl.VCNL4040, func(c *vcnl.Config) {
+			c.ProximityGain = vcnl.ProximityGainSingle8
+		}, vcnl.ErrUnsupportedFeature},
+		{"SunlightCurrent on VCNL4040", vcnl.VCNL4040, func(c *vcnl.Config) {
+			c.SunlightCurrent = vcnl.SunlightCurrent2
+		}, vcnl.ErrUnsupportedFeature},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			cfg := vcnl.DefaultConfig(tt.model)
+			tt.change(&cfg)
+
+			if err := cfg.Validate(tt.model); !errors.Is(err, tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, err)
+			}
+
+			// Apply validates before touching the sensor
+			s, dev := connect(t, tt.model)
+			dev.ClearTransactions()
+
+			if err := s.Apply(cfg); !errors.Is(err, tt.want) {
+				t.Errorf("Apply: expected %v, got %v", tt.want, err)
+			}
+
+			if n := len(dev.Transactions()); n != 0 {
+				t.Errorf("invalid config made %d transactions", n)
+			}
+		})
+	}
+}
+
+func TestValidateDefaultConfig(t *testing.T) {
+
+	for _, tc := range models {
+		if err := vcnl.DefaultConfig(tc.model).Validate(tc.model); err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.name, err)
+		}
+	}
+}
+
+// changedConfig returns a valid configuration with every setting changed
+// from DefaultConfig
+func changedConfig(m vcnl.Model) vcnl.Config {
+
+	cfg := vcnl.Config{
+		LEDCurrent:             vcnl.LED120mA,
+		IRDutyCycle:            vcnl.Duty160,
+		ProximityIT:            vcnl.ProximityIT2T,
+		ProximityResolution:    12,
+		ProximityPersistance:   vcnl.ProximityPersistance3,
+		ProximityInterrupt:     vcnl.InterruptBoth,
+		SmartPersistance:       false,
+		ActiveForceMode:        true,
+		ProximityLogicMode:     true,
+		ProximityHighThreshold: 3000,
+		ProximityLowThreshold:  150,
+		ProximityCancellation:  25,
+		ProximityEnabled:       false,
+		SunlightCancellation:   true,
+		AmbientIntegrationTime: vcnl.ALSIT320ms,
+		AmbientPersistance:     vcnl.AmbientPersistance4,
+		AmbientInterrupts:      true,
+		AmbientHighThreshold:   4000,
+		AmbientLowThreshold:    200,
+		AmbientEnabled:         false,
+		WhiteChannel:           false,
+	}
+
+	if m == vcnl.VCNL4040 {
+		cfg.ProximityMultiPulse = vcnl.ProximityMultiPulse4
+		return cfg
+	}
+
+	cfg.AmbientIntegrationTime = vcnl.ALSIT200ms
+	cfg.ProximityGain = vcnl.ProximityGainSingle8
+	cfg.ProximityTwoStepRatio = vcnl.ProximityTwoStep1
+	cfg.LowLEDCurrent = true
+	cfg.SunlightCurrent = vcnl.SunlightCurrent4
+	cfg.SunlightProtection = vcnl.SunlightProtection15
+	cfg.SunlightOutput = vcnl.SunlightOutputFF
+	cfg.AmbientHighDynamicRange = vcnl.AmbientRange2
+	cfg.AmbientSensitivityRange = vcnl.AmbientRange2
+
+	// the white channel can only be turned off on the VCNL4040
+	cfg.WhiteChannel = true
+
+	return cfg
+}
+
+func TestApplyReadConfigRoundTrip(t *testing.T) {
+
+	its := []vcnl.ProximityIT{
+		vcnl.ProximityIT1T, vcnl.ProximityIT15T, vcnl.ProximityIT2T, vcnl.ProximityIT25T,
+		vcnl.ProximityIT3T, vcnl.ProximityIT35T, vcnl.ProximityIT4T, vcnl.ProximityIT8T,
+	}
+
+	for _, tc := range models {
+		m := tc.model
+
+		t.Run(tc.name, func(t *testing.T) {
+
+			s, _ := connect(t, m)
+
+			configs := []vcnl.Config{vcnl.DefaultConfig(m), changedConfig(m)}
+
+			for _, it := range its {
+				cfg := changedConfig(m)
+				cfg.ProximityIT = it
+				configs = append(configs, cfg)
+			}
+
+			for _, want := range configs {
+				if err := s.Apply(want); err != nil {
+					t.Fatalf("error applying config %+v: %v", want, err)
+				}
+
+				got, err := s.ReadConfig()
+
+				if err != nil {
+					t.Fatalf("error reading config: %v", err)
+				}
+
+				if got != want {
+					t.Errorf("applied config %+v, read back %+v", want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestApplyProximityIntegrationTime(t *testing.T) {
+
+	s, _ := connect(t, vcnl.VCNL4040)
+
+	cfg := vcnl.DefaultConfig(vcnl.VCNL4040)
+	cfg.ProximityIT = 0
+	cfg.ProximityIntegrationTime = 3
+
+	if err := s.Apply(cfg); err != nil {
+		t.Fatalf("error applying config: %v", err)
+	}
+
+	got, err := s.ReadConfig()
+
+	if err != nil {
+		t.Fatalf("error reading config: %v", err)
+	}
+
+	// ReadConfig only reports the integration time in ProximityIT
+	if got.ProximityIT != vcnl.ProximityIT3T || got.ProximityIntegrationTime != 0 {
+		t.Errorf("read ProximityIT %d and ProximityIntegrationTime %d, want %d and 0",
+			got.ProximityIT, got.ProximityIntegrationTime, vcnl.ProximityIT3T)
+	}
+}
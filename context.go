@@ -0,0 +1,137 @@
+package vcnl40xx
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ctxMutex is a mutual exclusion lock which can be acquired with a context so
+// a goroutine waiting on a wedged bus can give up
+type ctxMutex struct {
+	once sync.Once
+	ch   chan struct{}
+}
+
+// init creates the lock channel on first use so the zero value is unlocked
+func (m *ctxMutex) init() {
+	m.once.Do(func() {
+		m.ch = make(chan struct{}, 1)
+	})
+}
+
+// Lock acquires the lock, blocking until it is available
+func (m *ctxMutex) Lock() {
+	m.init()
+	m.ch <- struct{}{}
+}
+
+// LockContext acquires the lock or returns the context error if ctx is done
+// first
+func (m *ctxMutex) LockContext(ctx context.Context) error {
+
+	m.init()
+
+	select {
+	case m.ch <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Unlock releases the lock
+func (m *ctxMutex) Unlock() {
+	<-m.ch
+}
+
+// pendingTimeout is the longest Close and ConnectBus wait for an abandoned
+// bus transaction to complete before releasing the bus
+const pendingTimeout = 500 * time.Millisecond
+
+// transact runs a bus transaction, returning the context error if ctx is
+// done before it completes.  An abandoned transaction continues in the
+// background and the next transaction waits for it so the bus is never used
+// concurrently.  The result of an abandoned transaction is discarded, for a
+// read of the INT_FLAG register this loses the flags it cleared on the
+// sensor.  The caller must hold the lock.
+func (s *Sensor) transact(ctx context.Context, fn func(bus Bus) error) error {
+
+	if s.bus == nil {
+		return ErrNotConnected
+	}
+
+	if s.pending != nil {
+		select {
+		case <-s.pending:
+			s.pending = nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	// a context which can not be cancelled needs no goroutine
+	if ctx.Done() == nil {
+		return fn(s.bus)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	bus := s.bus
+	done := make(chan struct{})
+
+	var err error
+
+	go func() {
+		err = fn(bus)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return err
+	case <-ctx.Done():
+		s.pending = done
+		return ctx.Err()
+	}
+}
+
+// waitPending waits up to pendingTimeout for an abandoned bus transaction to
+// complete, returning an error if it does not.  The caller must hold the
+// lock.
+func (s *Sensor) waitPending() error {
+
+	if s.pending == nil {
+		return nil
+	}
+
+	timer := time.NewTimer(pendingTimeout)
+	defer timer.Stop()
+
+	select {
+	case <-s.pending:
+		s.pending = nil
+		return nil
+	case <-timer.C:
+		s.pending = nil
+		return fmt.Errorf("abandoned bus transaction did not complete within %v", pendingTimeout)
+	}
+}
+
+// sleepContext pauses for the given duration or returns the context error if
+// ctx is done first
+func sleepContext(ctx context.Context, d time.Duration) error {
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,423 @@
+package vcnl40xx_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	vcnl "github.com/swdee/go-vcnl40xx"
+	"github.com/swdee/go-vcnl40xx/sim"
+)
+
+// stallBus wraps a simulated device with a bus which can be stalled, as a
+// wedged I2C bus would, and records overlapping use of the bus
+type stallBus struct {
+	*sim.Device
+	mu sync.Mutex
+	// gate blocks transactions until closed, nil if not stalled
+	gate chan struct{}
+	// active is the number of transactions in progress
+	active int
+	// overlapped is set if a transaction started while another was active
+	overlapped bool
+	// closedActive is set if the bus was closed during a transaction
+	closedActive bool
+}
+
+// stall blocks all following transactions until release is called
+func (b *stallBus) stall() {
+	b.mu.Lock()
+	b.gate = make(chan struct{})
+	b.mu.Unlock()
+}
+
+// release lets stalled transactions complete
+func (b *stallBus) release() {
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.gate != nil {
+		close(b.gate)
+		b.gate = nil
+	}
+}
+
+// enter marks the start of a transaction and waits while the bus is stalled
+func (b *stallBus) enter() {
+
+	b.mu.Lock()
+
+	gate := b.gate
+	b.active++
+
+	if b.active > 1 {
+		b.overlapped = true
+	}
+
+	b.mu.Unlock()
+
+	if gate != nil {
+		<-gate
+	}
+}
+
+// leave marks the end of a transaction
+func (b *stallBus) leave() {
+	b.mu.Lock()
+	b.active--
+	b.mu.Unlock()
+}
+
+// WriteBytes passes the write to the device once the bus is not stalled
+func (b *stallBus) WriteBytes(buf []byte) (int, error) {
+	b.enter()
+	defer b.leave()
+
+	return b.Device.WriteBytes(buf)
+}
+
+// WriteThenReadBytes passes the read to the device once the bus is not
+// stalled
+func (b *stallBus) WriteThenReadBytes(writeBuf, readBuf []byte) (int, int, error) {
+	b.enter()
+	defer b.leave()
+
+	return b.Device.WriteThenReadBytes(writeBuf, readBuf)
+}
+
+// Close closes the device, recording if a transaction was in progress
+func (b *stallBus) Close() error {
+
+	b.mu.Lock()
+
+	if b.active > 0 {
+		b.closedActive = true
+	}
+
+	b.mu.Unlock()
+
+	return b.Device.Close()
+}
+
+// connectStall returns a sensor connected to a stallable simulated device
+func connectStall(t *testing.T, m vcnl.Model) (*vcnl.Sensor, *stallBus) {
+
+	t.Helper()
+
+	dev, err := sim.New(m)
+
+	if err != nil {
+		t.Fatalf("error creating simulated device: %v", err)
+	}
+
+	bus := &stallBus{Device: dev}
+
+	s, err := vcnl.NewSensorWithBus(m, bus)
+
+	if err != nil {
+		t.Fatalf("error connecting sensor: %v", err)
+	}
+
+	// leave no transaction blocked once the test ends
+	t.Cleanup(bus.release)
+
+	return s, bus
+}
+
+// contextCalls are the context aware entry points of the Sensor
+var contextCalls = []struct {
+	name   string
+	models []vcnl.Model
+	call   func(ctx context.Context, s *vcnl.Sensor, m vcnl.Model) error
+}{
+	{"InitContext", nil, func(ctx context.Context, s *vcnl.Sensor, m vcnl.Model) error {
+		return s.InitContext(ctx)
+	}},
+	{"ApplyContext", nil, func(ctx context.Context, s *vcnl.Sensor, m vcnl.Model) error {
+		cfg := vcnl.DefaultConfig(m)
+		cfg.ProximityHighThreshold = 3000
+		return s.ApplyContext(ctx, cfg)
+	}},
+	{"ReadConfigContext", nil, func(ctx context.Context, s *vcnl.Sensor, m vcnl.Model) error {
+		_, err := s.ReadConfigContext(ctx)
+		return err
+	}},
+	{"GetProximityContext", nil, func(ctx context.Context, s *vcnl.Sensor, m vcnl.Model) error {
+		_, err := s.GetProximityContext(ctx)
+		return err
+	}},
+	{"GetAmbientContext", nil, func(ctx context.Context, s *vcnl.Sensor, m vcnl.Model) error {
+		_, err := s.GetAmbientContext(ctx)
+		return err
+	}},
+	{"GetWhiteContext", nil, func(ctx context.Context, s *vcnl.Sensor, m vcnl.Model) error {
+		_, err := s.GetWhiteContext(ctx)
+		return err
+	}},
+	{"GetLuxContext", nil, func(ctx context.Context, s *vcnl.Sensor, m vcnl.Model) error {
+		_, err := s.GetLuxContext(ctx)
+		return err
+	}},
+	{"ReadInterruptsContext", nil, func(ctx context.Context, s *vcnl.Sensor, m vcnl.Model) error {
+		_, err := s.ReadInterruptsContext(ctx)
+		return err
+	}},
+	{"TakeSingleProximityMeasurementContext", nil, func(ctx context.Context, s *vcnl.Sensor, m vcnl.Model) error {
+		return s.TakeSingleProximityMeasurementContext(ctx)
+	}},
+	{"MeasureProximityContext", nil, func(ctx context.Context, s *vcnl.Sensor, m vcnl.Model) error {
+		_, err := s.MeasureProximityContext(ctx)
+		return err
+	}},
+	{"GetProximityChannelContext", only4035, func(ctx context.Context, s *vcnl.Sensor, m vcnl.Model) error {
+		_, err := s.GetProximityChannelContext(ctx, 2)
+		return err
+	}},
+	{"GetProximityChannelsContext", only4035, func(ctx context.Context, s *vcnl.Sensor, m vcnl.Model) error {
+		_, err := s.GetProximityChannelsContext(ctx)
+		return err
+	}},
+	{"MeasureGestureContext", only4035, func(ctx context.Context, s *vcnl.Sensor, m vcnl.Model) error {
+		_, err := s.MeasureGestureContext(ctx)
+		return err
+	}},
+}
+
+func TestContextCancelled(t *testing.T) {
+
+	for _, tc := range models {
+		m := tc.model
+
+		for _, c := range contextCalls {
+			if !supports(c.models, m) {
+				continue
+			}
+
+			c := c
+
+			t.Run(tc.name+"/"+c.name, func(t *testing.T) {
+
+				s, dev := connect(t, m)
+				dev.ClearTransactions()
+
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+
+				if err := c.call(ctx, s, m); !errors.Is(err, context.Canceled) {
+					t.Fatalf("expected context.Canceled, got %v", err)
+				}
+
+				if n := len(dev.Transactions()); n != 0 {
+					t.Errorf("cancelled call made %d transactions", n)
+				}
+			})
+		}
+	}
+}
+
+func TestContextDeadlineOnStalledBus(t *testing.T) {
+
+	for _, tc := range models {
+		m := tc.model
+
+		for _, c := range contextCalls {
+			if !supports(c.models, m) {
+				continue
+			}
+
+			c := c
+
+			t.Run(tc.name+"/"+c.name, func(t *testing.T) {
+
+				s, bus := connectStall(t, m)
+				bus.stall()
+
+				ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+				defer cancel()
+
+				start := time.Now()
+				err := c.call(ctx, s, m)
+
+				if !errors.Is(err, context.DeadlineExceeded) {
+					t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+				}
+
+				if elapsed := time.Since(start); elapsed > 200*time.Millisecond {
+					t.Errorf("call returned %v after the deadline", elapsed)
+				}
+			})
+		}
+	}
+}
+
+func TestContextNextCallWaitsForAbandoned(t *testing.T) {
+
+	s, bus := connectStall(t, vcnl.VCNL4040)
+	bus.SetProximity(55)
+	bus.stall()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if _, err := s.GetProximityContext(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+
+	type result struct {
+		value uint16
+		err   error
+	}
+
+	done := make(chan result, 1)
+
+	go func() {
+		value, err := s.GetProximity()
+		done <- result{value, err}
+	}()
+
+	select {
+	case r := <-done:
+		t.Fatalf("call returned %+v while the abandoned transaction was running", r)
+	case <-time.After(30 * time.Millisecond):
+	}
+
+	bus.release()
+
+	select {
+	case r := <-done:
+		if r.err != nil || r.value != 55 {
+			t.Errorf("got %d, %v, want 55", r.value, r.err)
+		}
+
+	case <-time.After(time.Second):
+		t.Fatal("call did not complete after the bus was released")
+	}
+
+	if bus.overlapped {
+		t.Error("bus used by two transactions at once")
+	}
+}
+
+func TestContextCloseWaitsForAbandoned(t *testing.T) {
+
+	s, bus := connectStall(t, vcnl.VCNL4040)
+	s.EnablePowerDownOnClose()
+	bus.stall()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if _, err := s.GetProximityContext(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+
+	done := make(chan error, 1)
+
+	go func() {
+		done <- s.Close()
+	}()
+
+	select {
+	case err := <-done:
+		t.Fatalf("Close returned %v while the abandoned transaction was running", err)
+	case <-time.After(30 * time.Millisecond):
+	}
+
+	bus.release()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+
+	case <-time.After(time.Second):
+		t.Fatal("Close did not complete after the bus was released")
+	}
+
+	if bus.closedActive {
+		t.Error("bus closed while a transaction was using it")
+	}
+
+	if bus.overlapped {
+		t.Error("bus used by two transactions at once")
+	}
+
+	if !bus.Closed() {
+		t.Error("bus not closed")
+	}
+}
+
+func TestContextCloseWedgedBus(t *testing.T) {
+
+	s, bus := connectStall(t, vcnl.VCNL4040)
+	s.EnablePowerDownOnClose()
+	bus.stall()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if _, err := s.GetProximityContext(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+
+	// the abandoned transaction never completes, so Close gives up waiting
+	// and releases the bus without powering down
+	if err := s.Close(); err == nil {
+		t.Error("expected an error closing a wedged bus")
+	}
+
+	if !bus.Closed() {
+		t.Error("bus not closed")
+	}
+
+	if bus.overlapped {
+		t.Error("bus used by two transactions at once")
+	}
+}
+
+func TestInitKeepsOtherSettings(t *testing.T) {
+
+	for _, tc := range models {
+		m := tc.model
+
+		t.Run(tc.name, func(t *testing.T) {
+
+			s, _ := connect(t, m)
+
+			want := changedConfig(m)
+
+			if err := s.Apply(want); err != nil {
+				t.Fatalf("error applying config: %v", err)
+			}
+
+			if err := s.Init(); err != nil {
+				t.Fatalf("error initialising sensor: %v", err)
+			}
+
+			got, err := s.ReadConfig()
+
+			if err != nil {
+				t.Fatalf("error reading config: %v", err)
+			}
+
+			// Init only changes the settings of its own steps
+			def := vcnl.DefaultConfig(m)
+			want.LEDCurrent = def.LEDCurrent
+			want.IRDutyCycle = def.IRDutyCycle
+			want.ProximityIT = def.ProximityIT
+			want.ProximityResolution = def.ProximityResolution
+			want.SmartPersistance = def.SmartPersistance
+			want.ProximityEnabled = def.ProximityEnabled
+			want.AmbientIntegrationTime = def.AmbientIntegrationTime
+			want.AmbientEnabled = def.AmbientEnabled
+
+			if got != want {
+				t.Errorf("got config %+v, want %+v", got, want)
+			}
+		})
+	}
+}
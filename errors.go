@@ -0,0 +1,64 @@
+package vcnl40xx
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrUnsupportedModel is returned when the sensor model is not one
+	// supported by the driver
+	ErrUnsupportedModel = errors.New("unsupported sensor model")
+	// ErrUnsupportedFeature is returned when a command or setting is not
+	// available on the sensor model
+	ErrUnsupportedFeature = errors.New("command not supported for given sensor model")
+	// ErrNotConnected is returned when communicating with a sensor that has
+	// not been connected to a bus
+	ErrNotConnected = errors.New("sensor is not connected")
+	// ErrInvalidArgument is returned when a setting value or other argument
+	// is not one accepted by the driver
+	ErrInvalidArgument = errors.New("invalid argument")
+	// ErrIDMismatch is returned when the ID read from the sensor does not
+	// match the model.  Use errors.As with *IDMismatchError to get the IDs.
+	ErrIDMismatch = errors.New("unexpected sensor ID")
+)
+
+// IDMismatchError is returned on connecting when the sensor ID does not match
+// the model, it matches ErrIDMismatch with errors.Is
+type IDMismatchError struct {
+	// Got is the ID read from the sensor
+	Got uint8
+	// Want is the ID of the sensor model
+	Want uint8
+}
+
+// Error returns the error message
+func (e *IDMismatchError) Error() string {
+	return fmt.Sprintf("unexpected sensor ID value: 0x%02X, wanted 0x%02X", e.Got, e.Want)
+}
+
+// Is reports whether target is ErrIDMismatch
+func (e *IDMismatchError) Is(target error) bool {
+	return target == ErrIDMismatch
+}
+
+// BusError records a failed bus transaction and the command code it was
+// addressed to
+type BusError struct {
+	// Op is the direction of the transaction, "read" or "write"
+	Op string
+	// Cmd is the command code of the register accessed
+	Cmd byte
+	// Err is the error returned by the Bus
+	Err error
+}
+
+// Error returns the error message
+func (e *BusError) Error() string {
+	return fmt.Sprintf("i2c %s of command code 0x%02X failed: %v", e.Op, e.Cmd, e.Err)
+}
+
+// Unwrap returns the underlying Bus error
+func (e *BusError) Unwrap() error {
+	return e.Err
+}
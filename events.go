@@ -0,0 +1,460 @@
+package vcnl40xx
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// EventType defines the proximity and ambient light transitions reported by
+// an EventSubscriber
+type EventType int
+
+const (
+	// EventNear is emitted when an object comes close to the sensor
+	EventNear EventType = 1
+	// EventFar is emitted when an object moves away from the sensor
+	EventFar EventType = 2
+	// EventBright is emitted when the ambient light level rises
+	EventBright EventType = 3
+	// EventDark is emitted when the ambient light level falls
+	EventDark EventType = 4
+)
+
+// String returns the event name
+func (e EventType) String() string {
+	switch e {
+	case EventNear:
+		return "near"
+	case EventFar:
+		return "far"
+	case EventBright:
+		return "bright"
+	case EventDark:
+		return "dark"
+	default:
+		return fmt.Sprintf("EventType(%d)", int(e))
+	}
+}
+
+// Event is a proximity or ambient light transition
+type Event struct {
+	// Time the transition was confirmed
+	Time time.Time
+	// Type of transition
+	Type EventType
+	// Value is the proximity or ambient reading when the transition was
+	// confirmed, zero for transitions detected from interrupt flags
+	Value uint16
+}
+
+// EventConfig defines the thresholds and timing of event detection.  A
+// channel with both of its thresholds set to zero is not monitored.
+type EventConfig struct {
+	// NearThreshold is the proximity value at or above which an object is
+	// near
+	NearThreshold uint16
+	// FarThreshold is the proximity value at or below which an object is
+	// far, giving hysteresis below NearThreshold
+	FarThreshold uint16
+	// BrightThreshold is the ambient value at or above which it is bright
+	BrightThreshold uint16
+	// DarkThreshold is the ambient value at or below which it is dark,
+	// giving hysteresis below BrightThreshold
+	DarkThreshold uint16
+	// Dwell is the minimum time a new state must persist before its event
+	// is emitted
+	Dwell time.Duration
+	// Interval is the time between reads of the sensor, if zero the
+	// SampleInterval of the sensor is used
+	Interval time.Duration
+	// UseInterrupts programs the thresholds into the sensor and detects
+	// transitions from the INT_FLAG register instead of comparing readings.
+	// Polling INT_FLAG clears it, so while subscribed the flags are not
+	// seen by ReadInterrupts, IsClose, IsAway, IsLight, IsDark or gesture
+	// measurements.
+	UseInterrupts bool
+}
+
+// validate checks the thresholds give a hysteresis band
+func (c EventConfig) validate() error {
+
+	if c.Interval < 0 {
+		return fmt.Errorf("event interval %v must not be negative: %w", c.Interval, ErrInvalidArgument)
+	}
+
+	if (c.NearThreshold != 0 || c.FarThreshold != 0) && c.NearThreshold <= c.FarThreshold {
+		return fmt.Errorf("near threshold %d must be above far threshold %d: %w",
+			c.NearThreshold, c.FarThreshold, ErrInvalidArgument)
+	}
+
+	if (c.BrightThreshold != 0 || c.DarkThreshold != 0) && c.BrightThreshold <= c.DarkThreshold {
+		return fmt.Errorf("bright threshold %d must be above dark threshold %d: %w",
+			c.BrightThreshold, c.DarkThreshold, ErrInvalidArgument)
+	}
+
+	return nil
+}
+
+// proximityEnabled returns true if the proximity channel is monitored
+func (c EventConfig) proximityEnabled() bool {
+	return c.NearThreshold != 0 || c.FarThreshold != 0
+}
+
+// ambientEnabled returns true if the ambient channel is monitored
+func (c EventConfig) ambientEnabled() bool {
+	return c.BrightThreshold != 0 || c.DarkThreshold != 0
+}
+
+// eventState tracks the debounced state of one channel
+type eventState struct {
+	// state is the last emitted event, zero until the first event
+	state EventType
+	// candidate is the state waiting for the dwell time to pass
+	candidate EventType
+	// since is the time the candidate state was first seen
+	since time.Time
+}
+
+// update moves the channel towards target, a target of zero gives no new
+// information.  It returns the new state and true once a candidate state
+// has persisted for the dwell time.
+func (c *eventState) update(t time.Time, target EventType, dwell time.Duration) (EventType, bool) {
+
+	switch {
+	case target == 0:
+	case target == c.state:
+		c.candidate = 0
+		return 0, false
+	case target != c.candidate:
+		c.candidate = target
+		c.since = t
+	}
+
+	if c.candidate == 0 || t.Sub(c.since) < dwell {
+		return 0, false
+	}
+
+	c.state, c.candidate = c.candidate, 0
+
+	return c.state, true
+}
+
+// EventDetector turns a time sequence of readings or interrupt flags into
+// debounced Near, Far, Bright and Dark events.  The initial state of each
+// channel is emitted as its first event.
+type EventDetector struct {
+	cfg       EventConfig
+	proximity eventState
+	ambient   eventState
+}
+
+// NewEventDetector returns a detector using the given settings
+func NewEventDetector(cfg EventConfig) (*EventDetector, error) {
+
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+
+	return &EventDetector{cfg: cfg}, nil
+}
+
+// Update feeds a reading taken at time t to the detector and returns any
+// events confirmed by it
+func (d *EventDetector) Update(t time.Time, r Reading) []Event {
+
+	var events []Event
+
+	if d.cfg.proximityEnabled() {
+
+		var target EventType
+
+		switch {
+		case r.Proximity >= d.cfg.NearThreshold:
+			target = EventNear
+		case r.Proximity <= d.cfg.FarThreshold:
+			target = EventFar
+		default:
+			// inside the hysteresis band so hold the current state
+			d.proximity.candidate = 0
+		}
+
+		if e, ok := d.proximity.update(t, target, d.cfg.Dwell); ok {
+			events = append(events, Event{Time: t, Type: e, Value: r.Proximity})
+		}
+	}
+
+	if d.cfg.ambientEnabled() {
+
+		var target EventType
+
+		switch {
+		case r.Ambient >= d.cfg.BrightThreshold:
+			target = EventBright
+		case r.Ambient <= d.cfg.DarkThreshold:
+			target = EventDark
+		default:
+			d.ambient.candidate = 0
+		}
+
+		if e, ok := d.ambient.update(t, target, d.cfg.Dwell); ok {
+			events = append(events, Event{Time: t, Type: e, Value: r.Ambient})
+		}
+	}
+
+	return events
+}
+
+// UpdateFlags feeds interrupt flags read at time t to the detector and
+// returns any events confirmed by them.  It should be called regularly, even
+// when no flags are set, so pending states are confirmed once the dwell time
+// has passed.
+func (d *EventDetector) UpdateFlags(t time.Time, flags InterruptFlags) []Event {
+
+	var events []Event
+
+	if d.cfg.proximityEnabled() {
+		target := flagTarget(flags, FlagClose, FlagAway, EventNear, EventFar)
+
+		if e, ok := d.proximity.update(t, target, d.cfg.Dwell); ok {
+			events = append(events, Event{Time: t, Type: e})
+		}
+	}
+
+	if d.cfg.ambientEnabled() {
+		target := flagTarget(flags, FlagALSHigh, FlagALSLow, EventBright, EventDark)
+
+		if e, ok := d.ambient.update(t, target, d.cfg.Dwell); ok {
+			events = append(events, Event{Time: t, Type: e})
+		}
+	}
+
+	return events
+}
+
+// flagTarget returns the event selected by the high or low interrupt flag,
+// or zero if neither or both are set
+func flagTarget(flags, high, low InterruptFlags, highEvent, lowEvent EventType) EventType {
+
+	switch {
+	case flags.Has(high) && flags.Has(low):
+		return 0
+	case flags.Has(high):
+		return highEvent
+	case flags.Has(low):
+		return lowEvent
+	default:
+		return 0
+	}
+}
+
+// EventSubscriber reads the sensor in the background and delivers
+// proximity and ambient light transitions on a channel
+type EventSubscriber struct {
+	runner
+	sensor *Sensor
+	cfg    EventConfig
+	det    *EventDetector
+	events chan Event
+	// saved holds the interrupt settings to restore when UseInterrupts is set
+	saved Config
+	mu    sync.Mutex
+	// failed counts the polls skipped as a read failed
+	failed uint64
+	// readErr is the error of the most recent failed read
+	readErr error
+}
+
+// Subscribe starts detecting proximity and ambient light transitions in the
+// background until ctx is done, the subscriber is closed or the Sensor is
+// closed.  With UseInterrupts set the thresholds are written to the sensor
+// and its interrupts are enabled, the previous threshold and interrupt
+// settings are restored when the subscriber stops.  If the receiver does not
+// keep up with the Events channel reading pauses until it does, so no
+// transition is lost.  A poll is skipped if reading the sensor fails, see
+// Failed.
+func (s *Sensor) Subscribe(ctx context.Context, cfg EventConfig) (*EventSubscriber, error) {
+
+	det, err := NewEventDetector(cfg)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.Interval == 0 {
+		if cfg.Interval, err = s.SampleInterval(); err != nil {
+			return nil, err
+		}
+	}
+
+	sub := &EventSubscriber{
+		sensor: s,
+		cfg:    cfg,
+		det:    det,
+		events: make(chan Event, 16),
+	}
+
+	if cfg.UseInterrupts {
+		if sub.saved, err = s.ReadConfigContext(ctx); err != nil {
+			return nil, err
+		}
+
+		if err := s.enableEventInterrupts(cfg); err != nil {
+			// do not leave the sensor with some of the settings changed
+			sub.restore()
+			return nil, err
+		}
+	}
+
+	sub.start(ctx, cfg.Interval, sub.tick, sub.finish)
+
+	return sub, nil
+}
+
+// enableEventInterrupts writes the event thresholds to the sensor and
+// enables the interrupts of the monitored channels
+func (s *Sensor) enableEventInterrupts(cfg EventConfig) error {
+
+	if cfg.proximityEnabled() {
+		if err := s.SetProximityHighThreshold(cfg.NearThreshold); err != nil {
+			return err
+		}
+
+		if err := s.SetProximityLowThreshold(cfg.FarThreshold); err != nil {
+			return err
+		}
+
+		if err := s.SetProximityInterruptType(InterruptBoth); err != nil {
+			return err
+		}
+	}
+
+	if cfg.ambientEnabled() {
+		if err := s.SetALSHighThreshold(cfg.BrightThreshold); err != nil {
+			return err
+		}
+
+		if err := s.SetALSLowThreshold(cfg.DarkThreshold); err != nil {
+			return err
+		}
+
+		if err := s.EnableAmbientInterrupts(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Events returns the channel events are delivered on.  The channel is closed
+// when the subscriber stops.
+func (sub *EventSubscriber) Events() <-chan Event {
+	return sub.events
+}
+
+// Failed returns the number of polls skipped as reading the sensor failed
+// and the error of the most recent failure
+func (sub *EventSubscriber) Failed() (uint64, error) {
+
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+
+	return sub.failed, sub.readErr
+}
+
+// tick reads the sensor once and delivers the events confirmed, waiting for
+// the receiver if the channel is full
+func (sub *EventSubscriber) tick(ctx context.Context) error {
+
+	events, err := sub.poll(ctx)
+
+	if err != nil {
+		if stopping(ctx, err) {
+			return err
+		}
+
+		sub.mu.Lock()
+		sub.failed++
+		sub.readErr = err
+		sub.mu.Unlock()
+
+		return nil
+	}
+
+	for _, e := range events {
+		select {
+		case sub.events <- e:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return nil
+}
+
+// finish restores the interrupt settings and closes the Events channel
+func (sub *EventSubscriber) finish() error {
+
+	defer close(sub.events)
+
+	if !sub.cfg.UseInterrupts {
+		return nil
+	}
+
+	return sub.restore()
+}
+
+// restore writes back the threshold and interrupt settings from before the
+// subscriber was started, leaving the rest of the configuration as it is now
+func (sub *EventSubscriber) restore() error {
+
+	cfg, err := sub.sensor.ReadConfig()
+
+	if err != nil {
+		return err
+	}
+
+	cfg.ProximityHighThreshold = sub.saved.ProximityHighThreshold
+	cfg.ProximityLowThreshold = sub.saved.ProximityLowThreshold
+	cfg.ProximityInterrupt = sub.saved.ProximityInterrupt
+	cfg.AmbientHighThreshold = sub.saved.AmbientHighThreshold
+	cfg.AmbientLowThreshold = sub.saved.AmbientLowThreshold
+	cfg.AmbientInterrupts = sub.saved.AmbientInterrupts
+
+	return sub.sensor.Apply(cfg)
+}
+
+// poll reads the sensor once and returns the events confirmed
+func (sub *EventSubscriber) poll(ctx context.Context) ([]Event, error) {
+
+	now := time.Now()
+
+	if sub.cfg.UseInterrupts {
+
+		flags, err := sub.sensor.ReadInterruptsContext(ctx)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return sub.det.UpdateFlags(now, flags), nil
+	}
+
+	var r Reading
+	var err error
+
+	if sub.cfg.proximityEnabled() {
+		if r.Proximity, err = sub.sensor.GetProximityContext(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	if sub.cfg.ambientEnabled() {
+		if r.Ambient, err = sub.sensor.GetAmbientContext(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	return sub.det.Update(now, r), nil
+}
@@ -0,0 +1,235 @@
+package vcnl40xx_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	vcnl "github.com/swdee/go-vcnl40xx"
+)
+
+// nextEvent waits for the next event from the subscriber
+func nextEvent(t *testing.T, sub *vcnl.EventSubscriber) vcnl.Event {
+
+	t.Helper()
+
+	select {
+	case e, ok := <-sub.Events():
+		if !ok {
+			t.Fatalf("events channel closed, error: %v", sub.Err())
+		}
+
+		return e
+
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for event")
+	}
+
+	return vcnl.Event{}
+}
+
+func TestSubscribeValidation(t *testing.T) {
+
+	s, _ := connect(t, vcnl.VCNL4040)
+
+	configs := []vcnl.EventConfig{
+		{NearThreshold: 2000, FarThreshold: 150, Interval: -time.Millisecond},
+		{NearThreshold: 150, FarThreshold: 2000},
+		{BrightThreshold: 100, DarkThreshold: 100},
+	}
+
+	for _, cfg := range configs {
+
+		_, err := s.Subscribe(context.Background(), cfg)
+
+		if !errors.Is(err, vcnl.ErrInvalidArgument) {
+			t.Errorf("%+v: expected ErrInvalidArgument, got %v", cfg, err)
+		}
+	}
+}
+
+func TestSubscribeDeliversEveryTransition(t *testing.T) {
+
+	s, dev := connect(t, vcnl.VCNL4040)
+
+	// more transitions than the events channel can buffer
+	var values []uint16
+
+	for i := 0; i < 20; i++ {
+		values = append(values, 2500, 50)
+	}
+
+	dev.SetProximity(values...)
+
+	sub, err := s.Subscribe(context.Background(), vcnl.EventConfig{
+		NearThreshold: 2000,
+		FarThreshold:  150,
+		Interval:      time.Millisecond,
+	})
+
+	if err != nil {
+		t.Fatalf("error subscribing: %v", err)
+	}
+
+	defer sub.Close()
+
+	// let the channel fill before receiving
+	time.Sleep(50 * time.Millisecond)
+
+	for i := 0; i < len(values); i++ {
+
+		want := vcnl.EventNear
+
+		if i%2 == 1 {
+			want = vcnl.EventFar
+		}
+
+		if e := nextEvent(t, sub); e.Type != want {
+			t.Fatalf("event %d is %v, want %v", i, e.Type, want)
+		}
+	}
+}
+
+func TestSubscribeInterruptsRestoresSettings(t *testing.T) {
+
+	for _, tc := range models {
+		m := tc.model
+
+		t.Run(tc.name, func(t *testing.T) {
+
+			s, dev := connect(t, m)
+			configure(t, s)
+
+			cc := commandCodes(m)
+			want := configRegisters(dev, m)
+
+			sub, err := s.Subscribe(context.Background(), vcnl.EventConfig{
+				NearThreshold:   2000,
+				FarThreshold:    150,
+				BrightThreshold: 3000,
+				DarkThreshold:   50,
+				Interval:        time.Millisecond,
+				UseInterrupts:   true,
+			})
+
+			if err != nil {
+				t.Fatalf("error subscribing: %v", err)
+			}
+
+			if got := dev.Register(cc.PS_THDH); got != 2000 {
+				t.Errorf("proximity high threshold is %d, want 2000", got)
+			}
+
+			if got := dev.Register(cc.ALS_THDL); got != 50 {
+				t.Errorf("ambient low threshold is %d, want 50", got)
+			}
+
+			dev.RaiseInterrupt(registers(m).INT_FLAG_CLOSE)
+
+			if e := nextEvent(t, sub); e.Type != vcnl.EventNear {
+				t.Errorf("event is %v, want %v", e.Type, vcnl.EventNear)
+			}
+
+			if err := sub.Close(); err != nil {
+				t.Fatalf("error closing subscriber: %v", err)
+			}
+
+			if _, ok := <-sub.Events(); ok {
+				t.Error("events channel not closed")
+			}
+
+			got := configRegisters(dev, m)
+
+			for cmd, value := range want {
+				if got[cmd] != value {
+					t.Errorf("command code 0x%02X restored as 0x%04X, want 0x%04X", cmd, got[cmd], value)
+				}
+			}
+		})
+	}
+}
+
+func TestSubscribeStops(t *testing.T) {
+
+	s, _ := connect(t, vcnl.VCNL4040)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	sub, err := s.Subscribe(ctx, vcnl.EventConfig{
+		NearThreshold: 2000,
+		FarThreshold:  150,
+		Interval:      time.Millisecond,
+	})
+
+	if err != nil {
+		t.Fatalf("error subscribing: %v", err)
+	}
+
+	cancel()
+
+	select {
+	case <-sub.Done():
+	case <-time.After(time.Second):
+		t.Fatal("subscriber did not stop on context cancellation")
+	}
+
+	if err := sub.Err(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestSubscribeSkipsFailedPoll(t *testing.T) {
+
+	for _, interrupts := range []bool{false, true} {
+		name := "polling"
+
+		if interrupts {
+			name = "interrupts"
+		}
+
+		t.Run(name, func(t *testing.T) {
+
+			s, dev := connect(t, vcnl.VCNL4040)
+
+			dev.SetProximity(2500)
+
+			// the first poll fails, polling makes no transactions before
+			// the first poll and with interrupts the close flag is raised
+			// after the failure is set
+			glitch := errors.New("bus glitch")
+
+			if !interrupts {
+				dev.FailNext(1, glitch)
+			}
+
+			sub, err := s.Subscribe(context.Background(), vcnl.EventConfig{
+				NearThreshold: 2000,
+				FarThreshold:  150,
+				Interval:      time.Millisecond,
+				UseInterrupts: interrupts,
+			})
+
+			if err != nil {
+				t.Fatalf("error subscribing: %v", err)
+			}
+
+			defer sub.Close()
+
+			if interrupts {
+				dev.FailNext(1, glitch)
+				dev.RaiseInterrupt(vcnl.Registers4040().INT_FLAG_CLOSE)
+			}
+
+			if e := nextEvent(t, sub); e.Type != vcnl.EventNear {
+				t.Errorf("event is %v, want %v", e.Type, vcnl.EventNear)
+			}
+
+			failed, err := sub.Failed()
+
+			if failed != 1 || !errors.Is(err, glitch) {
+				t.Errorf("got %d failed polls with error %v, want 1 with %v", failed, err, glitch)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package vcnl40xx
+
+import (
+	"fmt"
+)
+
+// ProximityGain defines the proximity gain modes of the VCNL4030 and
+// VCNL4035
+type ProximityGain uint8
+
+const (
+	// ProximityGainTwoStep uses two-step gain mode with the ratio set by
+	// SetProximityTwoStepRatio
+	ProximityGainTwoStep ProximityGain = 1
+	// ProximityGainSingle8 uses single gain mode at x8 sensitivity
+	ProximityGainSingle8 ProximityGain = 2
+	// ProximityGainSingle1 uses single gain mode at x1 sensitivity
+	ProximityGainSingle1 ProximityGain = 3
+)
+
+// ProximityTwoStepRatio defines the sensitivity of proximity two-step gain
+// mode on the VCNL4030 and VCNL4035
+type ProximityTwoStepRatio uint8
+
+const (
+	ProximityTwoStep1 ProximityTwoStepRatio = 1
+	ProximityTwoStep4 ProximityTwoStepRatio = 4
+)
+
+// SetProximityGain sets the proximity gain mode on the VCNL4030 and VCNL4035
+func (s *Sensor) SetProximityGain(val ProximityGain) error {
+
+	if s.model != VCNL4030 && s.model != VCNL4035 {
+		return ErrUnsupportedFeature
+	}
+
+	gainValue, ok := lookup(proximityGainOptions(s.reg), uint16(val))
+
+	if !ok {
+		return fmt.Errorf("unknown proximity gain mode %d: %w", val, ErrInvalidArgument)
+	}
+
+	return s.bitMask(s.cc.PS_CONF2, UPPER, s.reg.PS_GAIN_MASK, gainValue)
+}
+
+// GetProximityGain returns the proximity gain mode on the VCNL4030 and
+// VCNL4035
+func (s *Sensor) GetProximityGain() (ProximityGain, error) {
+
+	if s.model != VCNL4030 && s.model != VCNL4035 {
+		return 0, ErrUnsupportedFeature
+	}
+
+	v, err := s.getField("proximity gain", s.cc.PS_CONF2, UPPER,
+		proximityGainOptions(s.reg), s.reg.PS_GAIN_MASK)
+
+	return ProximityGain(v), err
+}
+
+// SetProximityTwoStepRatio sets the sensitivity used in proximity two-step
+// gain mode on the VCNL4030 and VCNL4035
+func (s *Sensor) SetProximityTwoStepRatio(val ProximityTwoStepRatio) error {
+
+	if s.model != VCNL4030 && s.model != VCNL4035 {
+		return ErrUnsupportedFeature
+	}
+
+	nsValue, ok := lookup(proximityTwoStepOptions(s.reg), uint16(val))
+
+	if !ok {
+		return fmt.Errorf("unknown proximity two-step ratio %d: %w", val, ErrInvalidArgument)
+	}
+
+	return s.bitMask(s.cc.PS_CONF2, UPPER, s.reg.PS_NS_MASK, nsValue)
+}
+
+// GetProximityTwoStepRatio returns the sensitivity used in proximity
+// two-step gain mode on the VCNL4030 and VCNL4035
+func (s *Sensor) GetProximityTwoStepRatio() (ProximityTwoStepRatio, error) {
+
+	if s.model != VCNL4030 && s.model != VCNL4035 {
+		return 0, ErrUnsupportedFeature
+	}
+
+	v, err := s.getField("proximity two-step ratio", s.cc.PS_CONF2, UPPER,
+		proximityTwoStepOptions(s.reg), s.reg.PS_NS_MASK)
+
+	return ProximityTwoStepRatio(v), err
+}
+
+// EnableLowLEDCurrent reduces the IR LED current set by SetLEDCurrent to 1/10
+// on the VCNL4030 and VCNL4035
+func (s *Sensor) EnableLowLEDCurrent() error {
+	if s.model != VCNL4030 && s.model != VCNL4035 {
+		return ErrUnsupportedFeature
+	}
+	return s.bitMask(s.cc.PS_CONF3, LOWER, s.reg.LED_I_LOW_MASK, s.reg.LED_I_LOW_ENABLE)
+}
+
+// DisableLowLEDCurrent returns the IR LED current to the value set by
+// SetLEDCurrent on the VCNL4030 and VCNL4035
+func (s *Sensor) DisableLowLEDCurrent() error {
+	if s.model != VCNL4030 && s.model != VCNL4035 {
+		return ErrUnsupportedFeature
+	}
+	return s.bitMask(s.cc.PS_CONF3, LOWER, s.reg.LED_I_LOW_MASK, s.reg.LED_I_LOW_DISABLE)
+}
+
+// IsLowLEDCurrent returns true if the IR LED low current mode is enabled on
+// the VCNL4030 and VCNL4035
+func (s *Sensor) IsLowLEDCurrent() (bool, error) {
+
+	if s.model != VCNL4030 && s.model != VCNL4035 {
+		return false, ErrUnsupportedFeature
+	}
+
+	conf3, err := s.readCommandLower(s.cc.PS_CONF3)
+
+	if err != nil {
+		return false, err
+	}
+
+	return conf3&^s.reg.LED_I_LOW_MASK == s.reg.LED_I_LOW_ENABLE, nil
+}
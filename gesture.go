@@ -0,0 +1,353 @@
+package vcnl40xx
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Gesture defines the gestures recognised from the VCNL4035 proximity
+// channels
+type Gesture int
+
+const (
+	GestureLeft  Gesture = 1
+	GestureRight Gesture = 2
+	GestureUp    Gesture = 3
+	GestureDown  Gesture = 4
+	GestureTap   Gesture = 5
+)
+
+// String returns the gesture name
+func (g Gesture) String() string {
+	switch g {
+	case GestureLeft:
+		return "left"
+	case GestureRight:
+		return "right"
+	case GestureUp:
+		return "up"
+	case GestureDown:
+		return "down"
+	case GestureTap:
+		return "tap"
+	default:
+		return fmt.Sprintf("Gesture(%d)", int(g))
+	}
+}
+
+// GestureEvent is a recognised gesture
+type GestureEvent struct {
+	// Time the gesture completed
+	Time time.Time
+	// Gesture recognised
+	Gesture Gesture
+}
+
+// LEDPosition is the position of an IR LED relative to the sensor, with X
+// increasing to the right and Y increasing upwards
+type LEDPosition struct {
+	X float64
+	Y float64
+}
+
+// GestureConfig defines the thresholds and timing windows of the gesture
+// recognizer
+type GestureConfig struct {
+	// Threshold is the proximity value a channel must rise above for an
+	// object to be detected
+	Threshold uint16
+	// Release is the proximity value all channels must drop below for the
+	// object to have left, giving hysteresis below Threshold
+	Release uint16
+	// MaxDuration is the longest time an object may be present for the
+	// movement to count as a gesture
+	MaxDuration time.Duration
+	// MinSwipe is the minimum spread in time between the channel peaks for
+	// the movement to count as a swipe, otherwise it is a tap
+	MinSwipe time.Duration
+	// Interval is the time between measurements when sampling the sensor
+	Interval time.Duration
+	// Layout is the position of the IR LEDs for channels 1, 2 and 3
+	Layout [3]LEDPosition
+}
+
+// DefaultGestureConfig returns the gesture settings for the VCNL4035
+// reference layout with LED 1 on the left, LED 2 on the right and LED 3
+// above the sensor
+func DefaultGestureConfig() GestureConfig {
+	return GestureConfig{
+		Threshold:   1000,
+		Release:     600,
+		MaxDuration: 1500 * time.Millisecond,
+		MinSwipe:    30 * time.Millisecond,
+		Interval:    10 * time.Millisecond,
+		Layout: [3]LEDPosition{
+			{X: -1, Y: 0},
+			{X: 1, Y: 0},
+			{X: 0, Y: 1},
+		},
+	}
+}
+
+// GestureRecognizer turns a time sequence of three channel proximity values
+// into gestures by comparing when each channel peaks
+type GestureRecognizer struct {
+	cfg GestureConfig
+	// active is set while an object is over the sensor
+	active bool
+	// start is the time the object was first detected
+	start time.Time
+	// peak holds the peak value of each channel
+	peak [3]uint16
+	// peakTime holds the time each channel peaked
+	peakTime [3]time.Time
+}
+
+// NewGestureRecognizer returns a recognizer using the given settings
+func NewGestureRecognizer(cfg GestureConfig) *GestureRecognizer {
+	return &GestureRecognizer{cfg: cfg}
+}
+
+// Update feeds a measurement of all three channels taken at time t to the
+// recognizer.  It returns the gesture and true once a gesture completes.
+func (r *GestureRecognizer) Update(t time.Time, data [3]uint16) (Gesture, bool) {
+
+	present := false
+	released := true
+
+	for _, v := range data {
+		if v > r.cfg.Threshold {
+			present = true
+		}
+		if v >= r.cfg.Release {
+			released = false
+		}
+	}
+
+	if !r.active {
+		if !present {
+			return 0, false
+		}
+
+		r.active = true
+		r.start = t
+		r.peak = [3]uint16{}
+	}
+
+	for i, v := range data {
+		if v > r.peak[i] {
+			r.peak[i] = v
+			r.peakTime[i] = t
+		}
+	}
+
+	if !released {
+		return 0, false
+	}
+
+	r.active = false
+
+	if t.Sub(r.start) > r.cfg.MaxDuration {
+		// object hovered too long to be a gesture
+		return 0, false
+	}
+
+	return r.classify()
+}
+
+// classify determines the gesture from the channel peak times
+func (r *GestureRecognizer) classify() (Gesture, bool) {
+
+	var seen []int
+
+	for i, p := range r.peak {
+		if p > r.cfg.Threshold {
+			seen = append(seen, i)
+		}
+	}
+
+	if len(seen) == 0 {
+		return 0, false
+	}
+
+	// mean peak time relative to the start of the gesture
+	var mean float64
+	var first, last time.Duration
+
+	for n, i := range seen {
+		d := r.peakTime[i].Sub(r.start)
+		mean += float64(d)
+
+		if n == 0 || d < first {
+			first = d
+		}
+		if n == 0 || d > last {
+			last = d
+		}
+	}
+
+	mean /= float64(len(seen))
+
+	if len(seen) < 2 || last-first < r.cfg.MinSwipe {
+		return GestureTap, true
+	}
+
+	// motion is from the LEDs that peaked early towards those that peaked
+	// late, so weight each LED position by its peak time offset
+	var vx, vy float64
+
+	for _, i := range seen {
+		offset := float64(r.peakTime[i].Sub(r.start)) - mean
+		vx += r.cfg.Layout[i].X * offset
+		vy += r.cfg.Layout[i].Y * offset
+	}
+
+	if math.Abs(vx) >= math.Abs(vy) {
+		if vx > 0 {
+			return GestureRight, true
+		}
+		return GestureLeft, true
+	}
+
+	if vy > 0 {
+		return GestureUp, true
+	}
+
+	return GestureDown, true
+}
+
+// GestureEngine samples the VCNL4035 proximity channels and delivers
+// recognised gestures on a channel
+type GestureEngine struct {
+	runner
+	sensor *Sensor
+	rec    *GestureRecognizer
+	events chan GestureEvent
+	// modes holds the active force, gesture mode and gesture interrupt bits
+	// of PS_CONF3 from before the engine was started
+	modes byte
+}
+
+// validate checks the settings can be used to sample the sensor
+func (c GestureConfig) validate() error {
+
+	if c.Interval <= 0 {
+		return fmt.Errorf("gesture interval %v must be above zero: %w", c.Interval, ErrInvalidArgument)
+	}
+
+	if c.MaxDuration <= 0 {
+		return fmt.Errorf("gesture max duration %v must be above zero: %w", c.MaxDuration, ErrInvalidArgument)
+	}
+
+	if c.Release > c.Threshold {
+		return fmt.Errorf("gesture release %d must not be above threshold %d: %w",
+			c.Release, c.Threshold, ErrInvalidArgument)
+	}
+
+	return nil
+}
+
+// StartGestures enables gesture mode, the gesture interrupt and active force
+// mode on the VCNL4035 and samples the proximity channels in the background
+// until ctx is done or the engine is closed, after which the modes are
+// restored to their previous settings.  A measurement which fails or times
+// out is skipped.  Events are dropped if the receiver does not keep up with
+// the Events channel.
+func (s *Sensor) StartGestures(ctx context.Context, cfg GestureConfig) (*GestureEngine, error) {
+
+	if s.model != VCNL4035 {
+		return nil, ErrUnsupportedFeature
+	}
+
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+
+	conf3, err := s.readCommandLower(s.cc.PS_CONF3)
+
+	if err != nil {
+		return nil, err
+	}
+
+	e := &GestureEngine{
+		sensor: s,
+		rec:    NewGestureRecognizer(cfg),
+		events: make(chan GestureEvent, 16),
+		modes:  conf3 &^ s.gestureModesMask(),
+	}
+
+	if err := s.EnableActiveForceMode(); err != nil {
+		return nil, err
+	}
+
+	enable := []func() error{
+		s.EnableGestureMode,
+		s.EnableGestureInterrupt,
+	}
+
+	for _, fn := range enable {
+		if err := fn(); err != nil {
+			// leave the modes as they were found
+			e.restore()
+			return nil, err
+		}
+	}
+
+	e.start(ctx, cfg.Interval, e.tick, e.finish)
+
+	return e, nil
+}
+
+// Events returns the channel gestures are delivered on.  The channel is
+// closed when the engine stops.
+func (e *GestureEngine) Events() <-chan GestureEvent {
+	return e.events
+}
+
+// tick takes a gesture measurement and delivers the gesture it completes,
+// if any.  A failed measurement is treated as no sample.
+func (e *GestureEngine) tick(ctx context.Context) error {
+
+	data, err := e.sensor.MeasureGestureContext(ctx)
+
+	if err != nil {
+		if stopping(ctx, err) {
+			return err
+		}
+
+		return nil
+	}
+
+	now := time.Now()
+
+	if g, ok := e.rec.Update(now, data); ok {
+		select {
+		case e.events <- GestureEvent{Time: now, Gesture: g}:
+		default:
+		}
+	}
+
+	return nil
+}
+
+// finish restores the sensor modes and closes the Events channel
+func (e *GestureEngine) finish() error {
+
+	defer close(e.events)
+
+	return e.restore()
+}
+
+// restore writes back the active force, gesture mode and gesture interrupt
+// settings from before the engine was started
+func (e *GestureEngine) restore() error {
+	return e.sensor.bitMask(e.sensor.cc.PS_CONF3, LOWER, e.sensor.gestureModesMask(), e.modes)
+}
+
+// gestureModesMask returns the PS_CONF3 mask clearing the active force,
+// gesture mode and gesture interrupt bits
+func (s *Sensor) gestureModesMask() byte {
+	return s.reg.PS_AF_MASK & s.reg.GESTURE_MODE_MASK & s.reg.GESTURE_INT_EN_MASK
+}
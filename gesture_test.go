@@ -0,0 +1,243 @@
+package vcnl40xx_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	vcnl "github.com/swdee/go-vcnl40xx"
+)
+
+// sample is a gesture measurement taken at a time offset
+type sample struct {
+	at   time.Duration
+	data [3]uint16
+}
+
+func TestGestureRecognizer(t *testing.T) {
+
+	// LED 1 on the left, LED 2 on the right and LED 3 above
+	cfg := vcnl.DefaultGestureConfig()
+
+	tests := []struct {
+		name    string
+		samples []sample
+		want    []vcnl.Gesture
+	}{
+		{
+			name: "right",
+			samples: []sample{
+				{0, [3]uint16{2000, 0, 0}},
+				{100 * time.Millisecond, [3]uint16{0, 2000, 0}},
+				{200 * time.Millisecond, [3]uint16{0, 0, 0}},
+			},
+			want: []vcnl.Gesture{vcnl.GestureRight},
+		},
+		{
+			name: "left",
+			samples: []sample{
+				{0, [3]uint16{0, 2000, 0}},
+				{100 * time.Millisecond, [3]uint16{2000, 0, 0}},
+				{200 * time.Millisecond, [3]uint16{0, 0, 0}},
+			},
+			want: []vcnl.Gesture{vcnl.GestureLeft},
+		},
+		{
+			name: "up",
+			samples: []sample{
+				{0, [3]uint16{2000, 2000, 0}},
+				{100 * time.Millisecond, [3]uint16{0, 0, 2000}},
+				{200 * time.Millisecond, [3]uint16{0, 0, 0}},
+			},
+			want: []vcnl.Gesture{vcnl.GestureUp},
+		},
+		{
+			name: "down",
+			samples: []sample{
+				{0, [3]uint16{0, 0, 2000}},
+				{100 * time.Millisecond, [3]uint16{2000, 2000, 0}},
+				{200 * time.Millisecond, [3]uint16{0, 0, 0}},
+			},
+			want: []vcnl.Gesture{vcnl.GestureDown},
+		},
+		{
+			name: "tap",
+			samples: []sample{
+				{0, [3]uint16{2000, 2000, 2000}},
+				{10 * time.Millisecond, [3]uint16{3000, 3000, 3000}},
+				{100 * time.Millisecond, [3]uint16{0, 0, 0}},
+			},
+			want: []vcnl.Gesture{vcnl.GestureTap},
+		},
+		{
+			name: "peaks within MinSwipe are a tap",
+			samples: []sample{
+				{0, [3]uint16{2000, 0, 0}},
+				{20 * time.Millisecond, [3]uint16{0, 2000, 0}},
+				{100 * time.Millisecond, [3]uint16{0, 0, 0}},
+			},
+			want: []vcnl.Gesture{vcnl.GestureTap},
+		},
+		{
+			name: "single channel is a tap",
+			samples: []sample{
+				{0, [3]uint16{2000, 0, 0}},
+				{200 * time.Millisecond, [3]uint16{0, 0, 0}},
+			},
+			want: []vcnl.Gesture{vcnl.GestureTap},
+		},
+		{
+			name: "below threshold",
+			samples: []sample{
+				{0, [3]uint16{1000, 0, 0}},
+				{100 * time.Millisecond, [3]uint16{0, 1000, 0}},
+				{200 * time.Millisecond, [3]uint16{0, 0, 0}},
+			},
+		},
+		{
+			name: "hover beyond MaxDuration",
+			samples: []sample{
+				{0, [3]uint16{2000, 0, 0}},
+				{time.Second, [3]uint16{0, 2000, 0}},
+				{2 * time.Second, [3]uint16{0, 0, 0}},
+			},
+		},
+		{
+			name: "gesture after hover",
+			samples: []sample{
+				{0, [3]uint16{2000, 2000, 2000}},
+				{2 * time.Second, [3]uint16{0, 0, 0}},
+				{3 * time.Second, [3]uint16{0, 2000, 0}},
+				{3100 * time.Millisecond, [3]uint16{2000, 0, 0}},
+				{3200 * time.Millisecond, [3]uint16{0, 0, 0}},
+			},
+			want: []vcnl.Gesture{vcnl.GestureLeft},
+		},
+		{
+			// dropping below Threshold but not Release keeps the object
+			// present, so both peaks are part of one swipe
+			name: "release hysteresis",
+			samples: []sample{
+				{0, [3]uint16{2000, 0, 0}},
+				{50 * time.Millisecond, [3]uint16{800, 0, 0}},
+				{100 * time.Millisecond, [3]uint16{0, 2000, 0}},
+				{200 * time.Millisecond, [3]uint16{0, 0, 0}},
+			},
+			want: []vcnl.Gesture{vcnl.GestureRight},
+		},
+		{
+			name: "below release ends the gesture",
+			samples: []sample{
+				{0, [3]uint16{2000, 0, 0}},
+				{50 * time.Millisecond, [3]uint16{500, 0, 0}},
+				{100 * time.Millisecond, [3]uint16{0, 2000, 0}},
+				{200 * time.Millisecond, [3]uint16{0, 0, 0}},
+			},
+			want: []vcnl.Gesture{vcnl.GestureTap, vcnl.GestureTap},
+		},
+	}
+
+	start := time.Now()
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+
+			r := vcnl.NewGestureRecognizer(cfg)
+
+			var got []vcnl.Gesture
+
+			for _, smp := range tc.samples {
+				if g, ok := r.Update(start.Add(smp.at), smp.data); ok {
+					got = append(got, g)
+				}
+			}
+
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got gestures %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestGestureEngine(t *testing.T) {
+
+	s, dev := connect(t, vcnl.VCNL4035)
+	cc := vcnl.CommandCodes4035()
+	r := vcnl.Registers4035()
+
+	modes := r.PS_AF_MASK & r.GESTURE_MODE_MASK & r.GESTURE_INT_EN_MASK
+	before := dev.Register(cc.PS_CONF3)
+
+	cfg := vcnl.DefaultGestureConfig()
+	cfg.Interval = time.Millisecond
+	cfg.MinSwipe = time.Millisecond
+
+	e, err := s.StartGestures(context.Background(), cfg)
+
+	if err != nil {
+		t.Fatalf("error starting gestures: %v", err)
+	}
+
+	conf3 := byte(dev.Register(cc.PS_CONF3))
+
+	checks := []struct {
+		name string
+		mask byte
+		want byte
+	}{
+		{"PS_AF", r.PS_AF_MASK, r.PS_AF_ENABLE},
+		{"GESTURE_MODE", r.GESTURE_MODE_MASK, r.GESTURE_MODE_ENABLE},
+		{"GESTURE_INT_EN", r.GESTURE_INT_EN_MASK, r.GESTURE_INT_ENABLE},
+	}
+
+	for _, c := range checks {
+		if got := conf3 &^ c.mask; got != c.want {
+			t.Errorf("%s is 0x%02X while running, want 0x%02X", c.name, got, c.want)
+		}
+	}
+
+	// a failed measurement must be skipped rather than stop the engine
+	dev.FailNext(1, errors.New("bus glitch"))
+
+	for triggers := dev.Triggers(); dev.Triggers() < triggers+2; {
+		select {
+		case <-e.Done():
+			t.Fatalf("engine stopped after a failed measurement, error: %v", e.Err())
+		case <-time.After(time.Millisecond):
+		}
+	}
+
+	// a swipe from LED 1 to LED 2 across the following measurements
+	dev.SetProximityChannel(1, 0, 2000, 2000, 0)
+	dev.SetProximityChannel(2, 0, 0, 0, 2000, 2000, 0)
+
+	select {
+	case ev, ok := <-e.Events():
+		if !ok {
+			t.Fatalf("events channel closed, error: %v", e.Err())
+		}
+
+		if ev.Gesture != vcnl.GestureRight {
+			t.Errorf("got gesture %v, want %v", ev.Gesture, vcnl.GestureRight)
+		}
+
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for gesture")
+	}
+
+	if err := e.Close(); err != nil {
+		t.Fatalf("error closing engine: %v", err)
+	}
+
+	if err := e.Err(); err != nil {
+		t.Errorf("engine stopped with error: %v", err)
+	}
+
+	after := dev.Register(cc.PS_CONF3)
+
+	if byte(after)&^modes != byte(before)&^modes {
+		t.Errorf("PS_CONF3 modes restored as 0x%04X, want 0x%04X", after, before)
+	}
+}
@@ -0,0 +1,205 @@
+package vcnl40xx
+
+import (
+	"context"
+	"fmt"
+)
+
+// The getters below decode the current register settings back to the units
+// used by their matching setters.  When the register cache is enabled values
+// are served from the cache, call Refresh first if the sensor may have been
+// configured outside of this driver or reset.
+
+// GetLEDCurrent returns the IR LED sink current in mA
+func (s *Sensor) GetLEDCurrent() (uint8, error) {
+	v, err := s.getField("LED current", s.cc.PS_MS, UPPER,
+		ledCurrentOptions(s.reg), s.reg.LED_I_MASK)
+	return uint8(v), err
+}
+
+// GetIRDutyCycle returns the IR LED duty cycle as 1/value, eg: 40, 80, 160,
+// or 320
+func (s *Sensor) GetIRDutyCycle() (uint16, error) {
+	return s.getField("IR duty cycle", s.cc.PS_CONF1, LOWER,
+		irDutyOptions(s.reg), s.reg.PS_DUTY_MASK)
+}
+
+// GetProximityIntegrationTime returns the proximity integration time in T.
+// Half step settings are rounded down, use GetProximityIT to distinguish them.
+func (s *Sensor) GetProximityIntegrationTime() (uint8, error) {
+	v, err := s.GetProximityIT()
+	return uint8(v / 2), err
+}
+
+// GetProximityIT returns the proximity integration time setting
+func (s *Sensor) GetProximityIT() (ProximityIT, error) {
+	v, err := s.getField("proximity integration time", s.cc.PS_CONF1, LOWER,
+		proximityITOptions(s.reg), s.reg.PS_IT_MASK)
+	return ProximityIT(v), err
+}
+
+// GetProximityResolution returns the proximity resolution as 12 or 16 bit
+func (s *Sensor) GetProximityResolution() (uint8, error) {
+	v, err := s.getField("proximity resolution", s.cc.PS_CONF2, UPPER,
+		proximityResolutionOptions(s.reg), s.reg.PS_HD_MASK)
+	return uint8(v), err
+}
+
+// GetProximityInterruptPersistance returns the proximity interrupt
+// persistance value
+func (s *Sensor) GetProximityInterruptPersistance() (ProximityPersistance, error) {
+	v, err := s.getField("proximity persistance", s.cc.PS_CONF1, LOWER,
+		proximityPersistanceOptions(s.reg), s.reg.PS_PERS_MASK)
+	return ProximityPersistance(v), err
+}
+
+// GetProximityInterruptType returns the proximity interrupt type
+func (s *Sensor) GetProximityInterruptType() (InterruptType, error) {
+	v, err := s.getField("proximity interrupt type", s.cc.PS_CONF2, UPPER,
+		interruptTypeOptions(s.reg), s.reg.PS_INT_MASK)
+	return InterruptType(v), err
+}
+
+// GetAmbientIntegrationTime returns the ambient light integration time in
+// milliseconds
+func (s *Sensor) GetAmbientIntegrationTime() (uint16, error) {
+	return s.getField("ambient integration time", s.cc.ALS_CONF, LOWER,
+		ambientITOptions(s.model, s.reg), s.reg.ALS_IT_MASK)
+}
+
+// GetAmbientInterruptPersistance returns the ambient interrupt persistance
+// value
+func (s *Sensor) GetAmbientInterruptPersistance() (AmbientPersistance, error) {
+	v, err := s.getField("ambient persistance", s.cc.ALS_CONF, LOWER,
+		ambientPersistanceOptions(s.reg), s.reg.ALS_PERS_MASK)
+	return AmbientPersistance(v), err
+}
+
+// GetProximityCancellation returns the proximity cancellation value
+func (s *Sensor) GetProximityCancellation() (uint16, error) {
+	return s.readCommand(s.cc.PS_CANC)
+}
+
+// GetALSHighThreshold returns the ambient light high interrupt threshold
+func (s *Sensor) GetALSHighThreshold() (uint16, error) {
+	return s.readCommand(s.cc.ALS_THDH)
+}
+
+// GetALSLowThreshold returns the ambient light low interrupt threshold
+func (s *Sensor) GetALSLowThreshold() (uint16, error) {
+	return s.readCommand(s.cc.ALS_THDL)
+}
+
+// GetProximityHighThreshold returns the proximity high interrupt threshold
+func (s *Sensor) GetProximityHighThreshold() (uint16, error) {
+	return s.readCommand(s.cc.PS_THDH)
+}
+
+// GetProximityLowThreshold returns the proximity low interrupt threshold
+func (s *Sensor) GetProximityLowThreshold() (uint16, error) {
+	return s.readCommand(s.cc.PS_THDL)
+}
+
+// IsProximityPoweredOn returns true if the proximity sensor is powered on
+func (s *Sensor) IsProximityPoweredOn() (bool, error) {
+	return s.isSet(s.cc.PS_CONF1, LOWER, s.reg.PS_SD_MASK, s.reg.PS_SD_POWER_ON)
+}
+
+// IsAmbientPoweredOn returns true if the ambient light sensor is powered on
+func (s *Sensor) IsAmbientPoweredOn() (bool, error) {
+	return s.isSet(s.cc.ALS_CONF, LOWER, s.reg.ALS_SD_MASK, s.reg.ALS_SD_POWER_ON)
+}
+
+// IsWhitePoweredOn returns true if the white channel sensor is powered on
+// on the VCNL4030 and VCNL4035
+func (s *Sensor) IsWhitePoweredOn() (bool, error) {
+
+	if s.model != VCNL4030 && s.model != VCNL4035 {
+		return false, ErrUnsupportedFeature
+	}
+
+	return s.isSet(s.cc.ALS_CONF2, UPPER, s.reg.WHITE_SD_MASK, s.reg.WHITE_SD_POWER_ON)
+}
+
+// IsWhiteChannel returns true if the white measurement channel is enabled
+func (s *Sensor) IsWhiteChannel() (bool, error) {
+	return s.isSet(s.cc.PS_MS, UPPER, s.reg.WHITE_EN_MASK, s.reg.WHITE_ENABLE)
+}
+
+// IsSmartPersistance returns true if proximity smart persistence is enabled
+func (s *Sensor) IsSmartPersistance() (bool, error) {
+	return s.isSet(s.cc.PS_CONF3, LOWER, s.reg.PS_SMART_PERS_MASK, s.reg.PS_SMART_PERS_ENABLE)
+}
+
+// IsActiveForceMode returns true if proximity active force mode is enabled
+func (s *Sensor) IsActiveForceMode() (bool, error) {
+	return s.isSet(s.cc.PS_CONF3, LOWER, s.reg.PS_AF_MASK, s.reg.PS_AF_ENABLE)
+}
+
+// IsProximityLogicMode returns true if the proximity detection logic output
+// mode is enabled
+func (s *Sensor) IsProximityLogicMode() (bool, error) {
+
+	if s.model == VCNL4030 || s.model == VCNL4035 {
+		return s.isSet(s.cc.PS_CONF3, LOWER, s.reg.CONF3_PS_MS_MASK, s.reg.CONF3_PS_MS_OUTPUT_MODE)
+	}
+
+	return s.isSet(s.cc.PS_MS, UPPER, s.reg.PS_MS_MASK, s.reg.PS_MS_ENABLE)
+}
+
+// IsAmbientInterrupts returns true if ambient light interrupts are enabled
+func (s *Sensor) IsAmbientInterrupts() (bool, error) {
+	return s.isSet(s.cc.ALS_CONF, LOWER, s.reg.ALS_INT_EN_MASK, s.reg.ALS_INT_ENABLE)
+}
+
+// isSet reads the upper or lower byte of a register and returns true if the
+// field selected by the given mask holds the on bits
+func (s *Sensor) isSet(commandAddress byte, commandHeight bool, mask byte, on byte) (bool, error) {
+
+	commandValue, err := s.readCommand(commandAddress)
+
+	if err != nil {
+		return false, err
+	}
+
+	registerContents := byte(commandValue & 0xFF)
+
+	if commandHeight == UPPER {
+		registerContents = byte(commandValue >> 8)
+	}
+
+	return registerContents&^mask == on, nil
+}
+
+// getField reads the upper or lower byte of a register and decodes the
+// setting selected by the given mask
+func (s *Sensor) getField(name string, commandAddress byte, commandHeight bool,
+	options []option, mask byte) (uint16, error) {
+	return s.getFieldContext(context.Background(), name, commandAddress,
+		commandHeight, options, mask)
+}
+
+// getFieldContext is getField returning early if ctx is done
+func (s *Sensor) getFieldContext(ctx context.Context, name string, commandAddress byte,
+	commandHeight bool, options []option, mask byte) (uint16, error) {
+
+	commandValue, err := s.readCommandContext(ctx, commandAddress)
+
+	if err != nil {
+		return 0, err
+	}
+
+	registerContents := byte(commandValue & 0xFF)
+
+	if commandHeight == UPPER {
+		registerContents = byte(commandValue >> 8)
+	}
+
+	v, ok := decode(options, mask, registerContents)
+
+	if !ok {
+		return 0, fmt.Errorf("unrecognised %s register value 0x%02X", name, registerContents&^mask)
+	}
+
+	return v, nil
+}
@@ -0,0 +1,210 @@
+package vcnl40xx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// HealthEvent reports the result of a health check which found a problem
+type HealthEvent struct {
+	// Time the check was made
+	Time time.Time
+	// Registers are the command codes of the configuration registers which
+	// did not match the last applied settings
+	Registers []byte
+	// Recovered is set when the sensor was found reset and the last applied
+	// settings were written back to it
+	Recovered bool
+	// Err is the error which caused the check or recovery to fail
+	Err error
+}
+
+// errCacheDisabled is returned by health checks when the register cache is
+// not enabled
+var errCacheDisabled = errors.New("health check requires the register cache to be enabled")
+
+// CheckHealth verifies the sensor ID and compares the configuration
+// registers on the sensor against the last applied settings held in the
+// register cache.  If they differ the sensor has been reset, such as after a
+// brown-out, and the settings are written back to it.  Only registers held
+// in the cache are compared, which are those read or written since the
+// cache was enabled, StartHealthMonitor reads the others when it starts.
+// The returned event lists the registers found changed and is only valid if
+// err is nil.
+func (s *Sensor) CheckHealth() (HealthEvent, error) {
+	return s.CheckHealthContext(context.Background())
+}
+
+// CheckHealthContext is CheckHealth returning the context error if ctx is
+// done before the check completes
+func (s *Sensor) CheckHealthContext(ctx context.Context) (HealthEvent, error) {
+
+	event := HealthEvent{Time: time.Now()}
+
+	if err := s.mu.LockContext(ctx); err != nil {
+		return event, err
+	}
+
+	defer s.mu.Unlock()
+
+	if !s.cache {
+		return event, errCacheDisabled
+	}
+
+	id, err := s.busRead(ctx, s.cc.ID)
+
+	if err != nil {
+		return event, err
+	}
+
+	if uint8(id&0xFF) != s.model.ID() {
+		return event, &IDMismatchError{Got: uint8(id & 0xFF), Want: s.model.ID()}
+	}
+
+	for _, commandCode := range s.configRegisters() {
+
+		applied, ok := s.shadow[commandCode]
+
+		if !ok {
+			continue
+		}
+
+		value, err := s.busRead(ctx, commandCode)
+
+		if err != nil {
+			return event, err
+		}
+
+		if value != applied {
+			event.Registers = append(event.Registers, commandCode)
+		}
+	}
+
+	if len(event.Registers) == 0 {
+		return event, nil
+	}
+
+	// the sensor has lost its configuration so write back all of the
+	// applied settings, not just those found changed
+	for _, commandCode := range s.configRegisters() {
+
+		value, ok := s.shadow[commandCode]
+
+		if !ok {
+			continue
+		}
+
+		if err := s.writeRegister(ctx, commandCode, value); err != nil {
+			return event, fmt.Errorf("error restoring command code 0x%02X: %w", commandCode, err)
+		}
+	}
+
+	event.Recovered = true
+
+	return event, nil
+}
+
+// HealthConfig defines the checks made by a HealthMonitor
+type HealthConfig struct {
+	// Interval between health checks
+	Interval time.Duration
+}
+
+// HealthMonitor periodically checks the health of the sensor in the
+// background and re-applies its configuration after a reset
+type HealthMonitor struct {
+	runner
+	sensor *Sensor
+	events chan HealthEvent
+}
+
+// StartHealthMonitor runs CheckHealth at the configured interval until ctx
+// is done, the monitor is closed or the Sensor is closed.  The register
+// cache must be enabled, any configuration register it does not yet hold is
+// read from the sensor on starting so all of them are checked.  An event is
+// delivered each time the sensor was recovered or a check failed, checks
+// continue after a failure as the sensor may be powering up.  Events are
+// dropped if the receiver does not keep up with the Events channel.
+func (s *Sensor) StartHealthMonitor(ctx context.Context, cfg HealthConfig) (*HealthMonitor, error) {
+
+	if cfg.Interval <= 0 {
+		return nil, fmt.Errorf("health check interval %v must be above zero: %w",
+			cfg.Interval, ErrInvalidArgument)
+	}
+
+	if err := s.seedCache(ctx); err != nil {
+		return nil, err
+	}
+
+	m := &HealthMonitor{
+		sensor: s,
+		events: make(chan HealthEvent, 16),
+	}
+
+	m.start(ctx, cfg.Interval, m.tick, m.finish)
+
+	return m, nil
+}
+
+// seedCache reads the configuration registers not yet held in the register
+// cache so a health check compares all of them
+func (s *Sensor) seedCache(ctx context.Context) error {
+
+	if err := s.mu.LockContext(ctx); err != nil {
+		return err
+	}
+
+	defer s.mu.Unlock()
+
+	if !s.cache {
+		return errCacheDisabled
+	}
+
+	for _, commandCode := range s.configRegisters() {
+		if _, err := s.readRegister(ctx, commandCode); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Events returns the channel health events are delivered on.  The channel
+// is closed when the monitor stops.
+func (m *HealthMonitor) Events() <-chan HealthEvent {
+	return m.events
+}
+
+// tick checks the sensor health and delivers an event if it was recovered or
+// the check failed
+func (m *HealthMonitor) tick(ctx context.Context) error {
+
+	event, err := m.sensor.CheckHealthContext(ctx)
+
+	if ctx.Err() != nil || errors.Is(err, ErrNotConnected) {
+		return err
+	}
+
+	if err != nil {
+		event.Err = err
+	} else if !event.Recovered {
+		return nil
+	}
+
+	select {
+	case m.events <- event:
+	default:
+	}
+
+	return nil
+}
+
+// finish closes the Events channel
+func (m *HealthMonitor) finish() error {
+
+	close(m.events)
+
+	return nil
+}
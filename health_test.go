@@ -0,0 +1,278 @@
+package vcnl40xx_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	vcnl "github.com/swdee/go-vcnl40xx"
+	"github.com/swdee/go-vcnl40xx/sim"
+)
+
+// models are the sensor models every test is run against
+var models = []struct {
+	name  string
+	model vcnl.Model
+}{
+	{"VCNL4040", vcnl.VCNL4040},
+	{"VCNL4030", vcnl.VCNL4030},
+	{"VCNL4035", vcnl.VCNL4035},
+}
+
+// connect returns a sensor connected to a new simulated device
+func connect(t *testing.T, m vcnl.Model) (*vcnl.Sensor, *sim.Device) {
+
+	t.Helper()
+
+	dev, err := sim.New(m)
+
+	if err != nil {
+		t.Fatalf("error creating simulated device: %v", err)
+	}
+
+	s, err := vcnl.NewSensorWithBus(m, dev)
+
+	if err != nil {
+		t.Fatalf("error connecting sensor: %v", err)
+	}
+
+	return s, dev
+}
+
+// commandCodes returns the command codes of the given model
+func commandCodes(m vcnl.Model) vcnl.CommandCodes {
+
+	switch m {
+	case vcnl.VCNL4030:
+		return vcnl.CommandCodes4030()
+	case vcnl.VCNL4035:
+		return vcnl.CommandCodes4035()
+	default:
+		return vcnl.CommandCodes4040()
+	}
+}
+
+// registers returns the register bit definitions of the given model
+func registers(m vcnl.Model) vcnl.Registers {
+
+	switch m {
+	case vcnl.VCNL4030:
+		return vcnl.Registers4030()
+	case vcnl.VCNL4035:
+		return vcnl.Registers4035()
+	default:
+		return vcnl.Registers4040()
+	}
+}
+
+// configRegisters returns the contents of the configuration registers of the
+// simulated device
+func configRegisters(dev *sim.Device, m vcnl.Model) map[byte]uint16 {
+
+	cc := commandCodes(m)
+	regs := make(map[byte]uint16)
+
+	for _, cmd := range []byte{cc.ALS_CONF, cc.ALS_THDH, cc.ALS_THDL, cc.PS_CONF1,
+		cc.PS_CONF3, cc.PS_CANC, cc.PS_THDL, cc.PS_THDH} {
+		regs[cmd] = dev.Register(cmd)
+	}
+
+	return regs
+}
+
+// configure initialises the sensor and changes settings away from the
+// power on defaults so a reset can be detected in every register
+func configure(t *testing.T, s *vcnl.Sensor) {
+
+	t.Helper()
+
+	steps := []func() error{
+		s.Init,
+		func() error { return s.SetLEDCurrent(120) },
+		func() error { return s.SetProximityHighThreshold(3000) },
+		func() error { return s.SetProximityLowThreshold(200) },
+		func() error { return s.SetProximityCancellation(15) },
+		func() error { return s.SetALSHighThreshold(4000) },
+		func() error { return s.SetALSLowThreshold(100) },
+	}
+
+	for _, step := range steps {
+		if err := step(); err != nil {
+			t.Fatalf("error configuring sensor: %v", err)
+		}
+	}
+}
+
+func TestCheckHealthRecoversReset(t *testing.T) {
+
+	for _, tc := range models {
+		m := tc.model
+
+		t.Run(tc.name, func(t *testing.T) {
+
+			s, dev := connect(t, m)
+			s.EnableRegisterCache()
+			configure(t, s)
+
+			want := configRegisters(dev, m)
+
+			event, err := s.CheckHealth()
+
+			if err != nil {
+				t.Fatalf("error checking healthy sensor: %v", err)
+			}
+
+			if event.Recovered || len(event.Registers) != 0 {
+				t.Fatalf("healthy sensor reported as reset: %+v", event)
+			}
+
+			dev.Reset()
+
+			event, err = s.CheckHealth()
+
+			if err != nil {
+				t.Fatalf("error checking reset sensor: %v", err)
+			}
+
+			if !event.Recovered || len(event.Registers) == 0 {
+				t.Fatalf("reset not detected: %+v", event)
+			}
+
+			got := configRegisters(dev, m)
+
+			for cmd, value := range want {
+				if got[cmd] != value {
+					t.Errorf("command code 0x%02X restored as 0x%04X, want 0x%04X", cmd, got[cmd], value)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckHealthRequiresCache(t *testing.T) {
+
+	s, _ := connect(t, vcnl.VCNL4040)
+
+	if _, err := s.CheckHealth(); err == nil {
+		t.Fatal("expected error with register cache disabled")
+	}
+
+	if _, err := s.StartHealthMonitor(context.Background(),
+		vcnl.HealthConfig{Interval: time.Millisecond}); err == nil {
+		t.Fatal("expected error starting monitor with register cache disabled")
+	}
+}
+
+func TestCheckHealthIDMismatch(t *testing.T) {
+
+	s, dev := connect(t, vcnl.VCNL4040)
+	s.EnableRegisterCache()
+
+	dev.SetRegister(vcnl.CommandCodes4040().ID, 0x0000)
+
+	_, err := s.CheckHealth()
+
+	var mismatch *vcnl.IDMismatchError
+
+	if !errors.As(err, &mismatch) {
+		t.Fatalf("expected IDMismatchError, got %v", err)
+	}
+}
+
+func TestHealthMonitorRecoversReset(t *testing.T) {
+
+	for _, tc := range models {
+		m := tc.model
+
+		t.Run(tc.name, func(t *testing.T) {
+
+			s, dev := connect(t, m)
+
+			// configure before the cache is enabled so the monitor has to
+			// read the registers it checks when starting
+			configure(t, s)
+			s.EnableRegisterCache()
+
+			want := configRegisters(dev, m)
+
+			monitor, err := s.StartHealthMonitor(context.Background(),
+				vcnl.HealthConfig{Interval: time.Millisecond})
+
+			if err != nil {
+				t.Fatalf("error starting monitor: %v", err)
+			}
+
+			defer monitor.Close()
+
+			dev.Reset()
+
+			select {
+			case event := <-monitor.Events():
+				if event.Err != nil || !event.Recovered {
+					t.Fatalf("unexpected event: %+v", event)
+				}
+
+			case <-time.After(time.Second):
+				t.Fatal("timeout waiting for recovery event")
+			}
+
+			got := configRegisters(dev, m)
+
+			for cmd, value := range want {
+				if got[cmd] != value {
+					t.Errorf("command code 0x%02X restored as 0x%04X, want 0x%04X", cmd, got[cmd], value)
+				}
+			}
+		})
+	}
+}
+
+func TestHealthMonitorStops(t *testing.T) {
+
+	s, _ := connect(t, vcnl.VCNL4040)
+	s.EnableRegisterCache()
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	monitor, err := s.StartHealthMonitor(ctx, vcnl.HealthConfig{Interval: time.Millisecond})
+
+	if err != nil {
+		t.Fatalf("error starting monitor: %v", err)
+	}
+
+	cancel()
+
+	select {
+	case <-monitor.Done():
+	case <-time.After(time.Second):
+		t.Fatal("monitor did not stop on context cancellation")
+	}
+
+	if _, ok := <-monitor.Events(); ok {
+		t.Error("events channel not closed")
+	}
+
+	if err := monitor.Err(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if err := monitor.Close(); err != nil {
+		t.Errorf("unexpected error closing stopped monitor: %v", err)
+	}
+}
+
+func TestHealthMonitorInterval(t *testing.T) {
+
+	s, _ := connect(t, vcnl.VCNL4040)
+	s.EnableRegisterCache()
+
+	for _, interval := range []time.Duration{0, -time.Second} {
+
+		_, err := s.StartHealthMonitor(context.Background(), vcnl.HealthConfig{Interval: interval})
+
+		if !errors.Is(err, vcnl.ErrInvalidArgument) {
+			t.Errorf("interval %v: expected ErrInvalidArgument, got %v", interval, err)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package vcnl40xx
+
+import (
+	"context"
+)
+
+// InterruptFlags is the set of interrupt events read from the INT_FLAG
+// register
+type InterruptFlags uint8
+
+const (
+	// FlagAway is set when the proximity value drops below the low threshold
+	FlagAway InterruptFlags = 1 << iota
+	// FlagClose is set when the proximity value rises above the high threshold
+	FlagClose
+	// FlagALSHigh is set when the ambient light value rises above the high
+	// threshold
+	FlagALSHigh
+	// FlagALSLow is set when the ambient light value drops below the low
+	// threshold
+	FlagALSLow
+	// FlagSunlightProtect is set when the proximity sensor enters sunlight
+	// protection mode
+	FlagSunlightProtect
+	// FlagGestureReady is set on the VCNL4035 when a gesture mode
+	// measurement of all three proximity channels has completed
+	FlagGestureReady
+)
+
+// Has returns true if all of the given flags are set
+func (f InterruptFlags) Has(flag InterruptFlags) bool {
+	return f&flag == flag
+}
+
+// ReadInterrupts reads all interrupt flags in a single transaction.  As
+// reading the INT_FLAG register clears it on the sensor, this should be used
+// instead of calling IsClose, IsAway, IsLight and IsDark one after another,
+// which will lose any flag not asked for first.  An EventSubscriber using
+// interrupts reads the flags itself, so they will not be seen here while it
+// runs.
+func (s *Sensor) ReadInterrupts() (InterruptFlags, error) {
+	return s.ReadInterruptsContext(context.Background())
+}
+
+// ReadInterruptsContext is ReadInterrupts returning the context error if ctx
+// is done before the flags are read.  If ctx is done while the read is in
+// progress on the bus the sensor may still clear the flags, which are then
+// lost.
+func (s *Sensor) ReadInterruptsContext(ctx context.Context) (InterruptFlags, error) {
+
+	value, err := s.readCommandContext(ctx, s.cc.INT_FLAG)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return s.decodeInterrupts(byte(value >> 8)), nil
+}
+
+// decodeInterrupts maps the model specific INT_FLAG bits to InterruptFlags
+func (s *Sensor) decodeInterrupts(interruptFlags byte) InterruptFlags {
+
+	bits := []struct {
+		reg  uint8
+		flag InterruptFlags
+	}{
+		{s.reg.INT_FLAG_AWAY, FlagAway},
+		{s.reg.INT_FLAG_CLOSE, FlagClose},
+		{s.reg.INT_FLAG_ALS_HIGH, FlagALSHigh},
+		{s.reg.INT_FLAG_ALS_LOW, FlagALSLow},
+		{s.reg.INT_FLAG_PS_SP, FlagSunlightProtect},
+		{s.reg.INT_FLAG_GESTURE, FlagGestureReady},
+	}
+
+	var flags InterruptFlags
+
+	for _, b := range bits {
+		if b.reg != 0 && interruptFlags&b.reg != 0 {
+			flags |= b.flag
+		}
+	}
+
+	return flags
+}
@@ -0,0 +1,148 @@
+package vcnl40xx
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	// luxPerCount4040 is the ambient resolution of the VCNL4040 at 80ms
+	// integration time
+	luxPerCount4040 = 0.1
+	// luxIT4040 is the integration time luxPerCount4040 applies to
+	luxIT4040 = 80
+
+	// luxPerCount4030 is the ambient resolution of the VCNL4030 and VCNL4035
+	// at 50ms integration time with ALS_HD and ALS_NS at x1 dynamic range
+	luxPerCount4030 = 0.064
+	// luxIT4030 is the integration time luxPerCount4030 applies to
+	luxIT4030 = 50
+)
+
+// GetLux reads the ambient light value and converts it to lux using the
+// currently configured integration time and sensitivity.  If auto-ranging
+// is enabled the range is adjusted after the reading is taken.
+func (s *Sensor) GetLux() (float64, error) {
+	return s.GetLuxContext(context.Background())
+}
+
+// GetLuxContext is GetLux returning the context error if ctx is done before
+// the value is read
+func (s *Sensor) GetLuxContext(ctx context.Context) (float64, error) {
+
+	for {
+		if err := s.rangeMu.LockContext(ctx); err != nil {
+			return 0, err
+		}
+
+		if !s.auto.enabled {
+			break
+		}
+
+		wait := time.Until(s.auto.settle)
+
+		// there is no earlier reading to return until the first measurement
+		// after enabling auto-ranging completes, wait for it without holding
+		// the lock then check the state again
+		if wait > 0 && !s.auto.measured {
+			s.rangeMu.Unlock()
+
+			if err := sleepContext(ctx, wait); err != nil {
+				return 0, err
+			}
+
+			continue
+		}
+
+		defer s.rangeMu.Unlock()
+
+		return s.autoRangeLux(ctx)
+	}
+
+	defer s.rangeMu.Unlock()
+
+	resolution, err := s.ambientResolution(ctx)
+
+	if err != nil {
+		return 0, err
+	}
+
+	ambient, err := s.GetAmbientContext(ctx)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return float64(ambient) * resolution, nil
+}
+
+// GetWhiteLux reads the white light value and converts it to lux using the
+// same resolution as the ambient channel.  The white channel has a wider
+// spectral response so this is an approximation only.
+func (s *Sensor) GetWhiteLux() (float64, error) {
+
+	resolution, err := s.AmbientResolution()
+
+	if err != nil {
+		return 0, err
+	}
+
+	white, err := s.GetWhite()
+
+	if err != nil {
+		return 0, err
+	}
+
+	return float64(white) * resolution, nil
+}
+
+// AmbientResolution returns the lux per count of the ambient light value for
+// the currently configured integration time, and on VCNL4030 and VCNL4035
+// the ALS_HD and ALS_NS dynamic range settings
+func (s *Sensor) AmbientResolution() (float64, error) {
+	return s.ambientResolution(context.Background())
+}
+
+// ambientResolution is AmbientResolution returning early if ctx is done
+func (s *Sensor) ambientResolution(ctx context.Context) (float64, error) {
+
+	it, err := s.getFieldContext(ctx, "ambient integration time", s.cc.ALS_CONF, LOWER,
+		ambientITOptions(s.model, s.reg), s.reg.ALS_IT_MASK)
+
+	if err != nil {
+		return 0, err
+	}
+
+	hd, ns, err := s.ambientRanges(ctx)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return s.rangeResolution(alsRange{it, hd, ns}), nil
+}
+
+// ambientRanges returns the ALS_HD and ALS_NS dynamic range settings, which
+// are always x1 on the VCNL4040
+func (s *Sensor) ambientRanges(ctx context.Context) (AmbientRange, AmbientRange, error) {
+
+	if s.model != VCNL4030 && s.model != VCNL4035 {
+		return AmbientRange1, AmbientRange1, nil
+	}
+
+	hd, err := s.getFieldContext(ctx, "ambient high dynamic range", s.cc.ALS_CONF, LOWER,
+		ambientHDOptions(s.reg), s.reg.ALS_HD_MASK)
+
+	if err != nil {
+		return 0, 0, err
+	}
+
+	ns, err := s.getFieldContext(ctx, "ambient sensitivity range", s.cc.ALS_CONF2, UPPER,
+		ambientNSOptions(s.reg), s.reg.ALS_NS_MASK)
+
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return AmbientRange(hd), AmbientRange(ns), nil
+}
@@ -0,0 +1,88 @@
+package vcnl40xx_test
+
+import (
+	"math"
+	"testing"
+
+	vcnl "github.com/swdee/go-vcnl40xx"
+)
+
+// closeTo returns true if got is within a relative error of want
+func closeTo(got, want float64) bool {
+	return math.Abs(got-want) <= 1e-9*math.Max(1, math.Abs(want))
+}
+
+func TestAmbientResolution(t *testing.T) {
+
+	tests := []struct {
+		models []vcnl.Model
+		it     uint16
+		hd     vcnl.AmbientRange
+		ns     vcnl.AmbientRange
+		want   float64
+	}{
+		{only4040, 80, 0, 0, 0.1},
+		{only4040, 160, 0, 0, 0.05},
+		{only4040, 320, 0, 0, 0.025},
+		{only4040, 640, 0, 0, 0.0125},
+		{newer, 50, vcnl.AmbientRange1, vcnl.AmbientRange1, 0.064},
+		{newer, 100, vcnl.AmbientRange1, vcnl.AmbientRange1, 0.032},
+		{newer, 200, vcnl.AmbientRange1, vcnl.AmbientRange1, 0.016},
+		{newer, 400, vcnl.AmbientRange1, vcnl.AmbientRange1, 0.008},
+		{newer, 800, vcnl.AmbientRange1, vcnl.AmbientRange1, 0.004},
+		{newer, 50, vcnl.AmbientRange2, vcnl.AmbientRange1, 0.128},
+		{newer, 50, vcnl.AmbientRange1, vcnl.AmbientRange2, 0.128},
+		{newer, 50, vcnl.AmbientRange2, vcnl.AmbientRange2, 0.256},
+		{newer, 800, vcnl.AmbientRange2, vcnl.AmbientRange2, 0.016},
+	}
+
+	for _, tc := range models {
+		m := tc.model
+
+		t.Run(tc.name, func(t *testing.T) {
+			for _, tt := range tests {
+				if !supports(tt.models, m) {
+					continue
+				}
+
+				s, dev := connect(t, m)
+
+				if err := s.SetAmbientIntegrationTime(tt.it); err != nil {
+					t.Fatalf("error setting integration time: %v", err)
+				}
+
+				if tt.hd != 0 {
+					if err := s.SetAmbientHighDynamicRange(tt.hd); err != nil {
+						t.Fatalf("error setting ALS_HD: %v", err)
+					}
+
+					if err := s.SetAmbientSensitivityRange(tt.ns); err != nil {
+						t.Fatalf("error setting ALS_NS: %v", err)
+					}
+				}
+
+				resolution, err := s.AmbientResolution()
+
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+
+				if !closeTo(resolution, tt.want) {
+					t.Errorf("%dms HD x%d NS x%d: got %v lux/count, want %v",
+						tt.it, tt.hd, tt.ns, resolution, tt.want)
+				}
+
+				dev.SetAmbient(1000)
+				dev.SetWhite(2000)
+
+				if lux, err := s.GetLux(); err != nil || !closeTo(lux, 1000*tt.want) {
+					t.Errorf("%dms: GetLux got %v, %v, want %v", tt.it, lux, err, 1000*tt.want)
+				}
+
+				if lux, err := s.GetWhiteLux(); err != nil || !closeTo(lux, 2000*tt.want) {
+					t.Errorf("%dms: GetWhiteLux got %v, %v, want %v", tt.it, lux, err, 2000*tt.want)
+				}
+			}
+		})
+	}
+}
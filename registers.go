@@ -121,10 +121,21 @@ type Registers struct {
 	PS_SC_EN_ENABLE  uint8
 	PS_SC_EN_DISABLE uint8
 
+	// 4035
+	GESTURE_INT_EN_MASK uint8
+	GESTURE_INT_DISABLE uint8
+	GESTURE_INT_ENABLE  uint8
+
+	// 4035
+	GESTURE_MODE_MASK    uint8
+	GESTURE_MODE_DISABLE uint8
+	GESTURE_MODE_ENABLE  uint8
+
 	WHITE_EN_MASK uint8
 	WHITE_ENABLE  uint8
 	WHITE_DISABLE uint8
 
+	// 4040, on 4030 and 4035 see CONF3_PS_MS
 	PS_MS_MASK    uint8
 	PS_MS_DISABLE uint8
 	PS_MS_ENABLE  uint8
@@ -156,6 +167,8 @@ type Registers struct {
 	PS_SPO_MODE_0 uint8
 	PS_SPO_MODE_1 uint8
 
+	INT_FLAG_GESTURE  uint8
+	INT_FLAG_PS_SP    uint8
 	INT_FLAG_ALS_LOW  uint8
 	INT_FLAG_ALS_HIGH uint8
 	INT_FLAG_CLOSE    uint8
@@ -239,8 +252,8 @@ func Registers4040() Registers {
 		PS_TRIG_TRIGGER: 1 << 2,
 
 		PS_SC_EN_MASK:    ^uint8(1 << 0),
-		PS_SC_EN_ENABLE:  0,
-		PS_SC_EN_DISABLE: 1 << 0,
+		PS_SC_EN_ENABLE:  1 << 0,
+		PS_SC_EN_DISABLE: 0,
 
 		WHITE_EN_MASK: ^uint8(1 << 7),
 		WHITE_ENABLE:  0,
@@ -260,6 +273,7 @@ func Registers4040() Registers {
 		LED_180MA:  (1 << 2) | (1 << 1),
 		LED_200MA:  (1 << 2) | (1 << 1) | (1 << 0),
 
+		INT_FLAG_PS_SP:    1 << 6,
 		INT_FLAG_ALS_LOW:  1 << 5,
 		INT_FLAG_ALS_HIGH: 1 << 4,
 		INT_FLAG_CLOSE:    1 << 1,
@@ -368,17 +382,13 @@ func Registers4030() Registers {
 		CONF3_PS_MS_OUTPUT_MODE: 1 << 1,
 
 		PS_SC_EN_MASK:    ^uint8(1 << 0),
-		PS_SC_EN_ENABLE:  0,
-		PS_SC_EN_DISABLE: 1 << 0,
+		PS_SC_EN_ENABLE:  1 << 0,
+		PS_SC_EN_DISABLE: 0,
 
 		WHITE_EN_MASK: ^uint8(1 << 7),
 		WHITE_ENABLE:  0,
 		WHITE_DISABLE: 1 << 7,
 
-		PS_MS_MASK:    ^uint8(1 << 6),
-		PS_MS_DISABLE: 0,
-		PS_MS_ENABLE:  (1 << 6),
-
 		LED_I_MASK: ^uint8((1 << 2) | (1 << 1) | (1 << 0)),
 		LED_50MA:   0,
 		LED_75MA:   (1 << 0),
@@ -403,6 +413,7 @@ func Registers4030() Registers {
 		PS_SPO_MODE_0: 0,
 		PS_SPO_MODE_1: 1 << 3,
 
+		INT_FLAG_PS_SP:    1 << 6,
 		INT_FLAG_ALS_LOW:  1 << 5,
 		INT_FLAG_ALS_HIGH: 1 << 4,
 		INT_FLAG_CLOSE:    1 << 1,
@@ -511,17 +522,21 @@ func Registers4035() Registers {
 		CONF3_PS_MS_OUTPUT_MODE: 1 << 1,
 
 		PS_SC_EN_MASK:    ^uint8(1 << 0),
-		PS_SC_EN_ENABLE:  0,
-		PS_SC_EN_DISABLE: 1 << 0,
+		PS_SC_EN_ENABLE:  1 << 0,
+		PS_SC_EN_DISABLE: 0,
+
+		GESTURE_INT_EN_MASK: ^uint8(1 << 6),
+		GESTURE_INT_DISABLE: 0,
+		GESTURE_INT_ENABLE:  1 << 6,
+
+		GESTURE_MODE_MASK:    ^uint8(1 << 5),
+		GESTURE_MODE_DISABLE: 0,
+		GESTURE_MODE_ENABLE:  1 << 5,
 
 		WHITE_EN_MASK: ^uint8(1 << 7),
 		WHITE_ENABLE:  0,
 		WHITE_DISABLE: 1 << 7,
 
-		PS_MS_MASK:    ^uint8(1 << 6),
-		PS_MS_DISABLE: 0,
-		PS_MS_ENABLE:  (1 << 6),
-
 		LED_I_MASK: ^uint8((1 << 2) | (1 << 1) | (1 << 0)),
 		LED_50MA:   0,
 		LED_75MA:   (1 << 0),
@@ -546,6 +561,8 @@ func Registers4035() Registers {
 		PS_SPO_MODE_0: 0,
 		PS_SPO_MODE_1: 1 << 3,
 
+		INT_FLAG_GESTURE:  1 << 7,
+		INT_FLAG_PS_SP:    1 << 6,
 		INT_FLAG_ALS_LOW:  1 << 5,
 		INT_FLAG_ALS_HIGH: 1 << 4,
 		INT_FLAG_CLOSE:    1 << 1,
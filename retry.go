@@ -0,0 +1,154 @@
+package vcnl40xx
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// RetryPolicy defines how bus transactions failing with a transient error
+// are retried.  Reads of the INT_FLAG register are never retried, as a
+// failed read may still have cleared the flags on the sensor and a retry
+// would report them as unset.
+//
+// The Sensor lock is held while backing off between attempts, so a setter's
+// read and write back of a register are never interleaved with another
+// goroutine.  Other goroutines using the Sensor wait for the retries to
+// finish, keep Backoff and MaxBackoff short.
+type RetryPolicy struct {
+	// Attempts is the maximum number of times a transaction is tried, a
+	// value of 0 or 1 disables retries
+	Attempts int
+	// Backoff is the delay before the first retry, it is doubled for each
+	// retry after that
+	Backoff time.Duration
+	// MaxBackoff limits the delay between retries, 0 for no limit
+	MaxBackoff time.Duration
+	// Retryable are the errnos returned by the bus which are retried, eg:
+	// syscall.ETIMEDOUT
+	Retryable []syscall.Errno
+}
+
+// RetryStats are the counters of retried bus transactions
+type RetryStats struct {
+	// Retries is the number of retry attempts made
+	Retries uint64
+	// Recovered is the number of transactions which succeeded after a retry
+	Recovered uint64
+	// Exhausted is the number of transactions which failed with a retryable
+	// error after all attempts were used, including when the policy makes a
+	// single attempt
+	Exhausted uint64
+}
+
+// retryCounter holds the RetryStats with their own lock so they can be read
+// while a transaction is in progress
+type retryCounter struct {
+	mu    sync.Mutex
+	stats RetryStats
+}
+
+// DefaultRetryPolicy returns a policy which makes up to 3 attempts of each
+// transaction failing with EREMOTEIO (no acknowledgement from the sensor) or
+// ETIMEDOUT, backing off from 1ms
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		Attempts:   3,
+		Backoff:    time.Millisecond,
+		MaxBackoff: 10 * time.Millisecond,
+		Retryable:  defaultRetryable(),
+	}
+}
+
+// SetRetryPolicy sets the policy used to retry failed bus transactions.
+// Retries are disabled by default.
+func (s *Sensor) SetRetryPolicy(p RetryPolicy) {
+	s.mu.Lock()
+	s.retry = p
+	s.mu.Unlock()
+}
+
+// GetRetryPolicy returns the policy used to retry failed bus transactions
+func (s *Sensor) GetRetryPolicy() RetryPolicy {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.retry
+}
+
+// GetRetryStats returns the counters of retried bus transactions
+func (s *Sensor) GetRetryStats() RetryStats {
+	s.retries.mu.Lock()
+	defer s.retries.mu.Unlock()
+
+	return s.retries.stats
+}
+
+// ResetRetryStats sets the counters of retried bus transactions to zero
+func (s *Sensor) ResetRetryStats() {
+	s.retries.mu.Lock()
+	s.retries.stats = RetryStats{}
+	s.retries.mu.Unlock()
+}
+
+// retryable returns true if err is one of the policy's retryable errnos
+func (p RetryPolicy) retryable(err error) bool {
+
+	for _, errno := range p.Retryable {
+		if errors.Is(err, errno) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// transactRetry runs a bus transaction, retrying it according to the retry
+// policy.  The caller must hold the lock, which stays held while backing off.
+func (s *Sensor) transactRetry(ctx context.Context, fn func(bus Bus) error) error {
+
+	backoff := s.retry.Backoff
+
+	for attempt := 1; ; attempt++ {
+
+		err := s.transact(ctx, fn)
+
+		if err == nil {
+			if attempt > 1 {
+				s.countRetry(func(r *RetryStats) { r.Recovered++ })
+			}
+
+			return nil
+		}
+
+		if !s.retry.retryable(err) {
+			return err
+		}
+
+		if attempt >= s.retry.Attempts {
+			s.countRetry(func(r *RetryStats) { r.Exhausted++ })
+			return err
+		}
+
+		if err := sleepContext(ctx, backoff); err != nil {
+			return err
+		}
+
+		s.countRetry(func(r *RetryStats) { r.Retries++ })
+
+		backoff *= 2
+
+		if s.retry.MaxBackoff > 0 && backoff > s.retry.MaxBackoff {
+			backoff = s.retry.MaxBackoff
+		}
+	}
+}
+
+// countRetry updates the retry counters
+func (s *Sensor) countRetry(update func(r *RetryStats)) {
+	s.retries.mu.Lock()
+	update(&s.retries.stats)
+	s.retries.mu.Unlock()
+}
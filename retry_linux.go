@@ -0,0 +1,13 @@
+//go:build linux
+// +build linux
+
+package vcnl40xx
+
+import (
+	"syscall"
+)
+
+// defaultRetryable returns the errnos retried by DefaultRetryPolicy
+func defaultRetryable() []syscall.Errno {
+	return []syscall.Errno{syscall.EREMOTEIO, syscall.ETIMEDOUT}
+}
@@ -0,0 +1,233 @@
+//go:build linux
+// +build linux
+
+package vcnl40xx_test
+
+import (
+	"errors"
+	"syscall"
+	"testing"
+	"time"
+
+	vcnl "github.com/swdee/go-vcnl40xx"
+)
+
+func TestRetryPolicy(t *testing.T) {
+
+	s, _ := connect(t, vcnl.VCNL4040)
+
+	if p := s.GetRetryPolicy(); p.Attempts > 1 {
+		t.Errorf("retries enabled by default with policy %+v", p)
+	}
+
+	want := vcnl.DefaultRetryPolicy()
+	s.SetRetryPolicy(want)
+
+	got := s.GetRetryPolicy()
+
+	if got.Attempts != want.Attempts || got.Backoff != want.Backoff ||
+		got.MaxBackoff != want.MaxBackoff || len(got.Retryable) != len(want.Retryable) {
+		t.Errorf("got policy %+v, want %+v", got, want)
+	}
+}
+
+func TestRetryStats(t *testing.T) {
+
+	defaultPolicy := vcnl.DefaultRetryPolicy()
+	singlePolicy := vcnl.RetryPolicy{
+		Attempts:  1,
+		Retryable: []syscall.Errno{syscall.EREMOTEIO},
+	}
+
+	tests := []struct {
+		name string
+		// policy is the retry policy set, if any
+		policy *vcnl.RetryPolicy
+		// failures is the number of transactions failing with err
+		failures int
+		err      error
+		// call is the method tried
+		call    func(s *vcnl.Sensor) error
+		wantErr bool
+		want    vcnl.RetryStats
+	}{
+		{
+			name:     "recovered read",
+			policy:   &defaultPolicy,
+			failures: 2,
+			err:      syscall.EREMOTEIO,
+			call:     getProximity,
+			want:     vcnl.RetryStats{Retries: 2, Recovered: 1},
+		},
+		{
+			name:     "recovered write",
+			policy:   &defaultPolicy,
+			failures: 1,
+			err:      syscall.ETIMEDOUT,
+			call:     func(s *vcnl.Sensor) error { return s.SetProximityHighThreshold(3000) },
+			want:     vcnl.RetryStats{Retries: 1, Recovered: 1},
+		},
+		{
+			name:     "exhausted",
+			policy:   &defaultPolicy,
+			failures: 3,
+			err:      syscall.EREMOTEIO,
+			call:     getProximity,
+			wantErr:  true,
+			want:     vcnl.RetryStats{Retries: 2, Exhausted: 1},
+		},
+		{
+			name:     "not retryable",
+			policy:   &defaultPolicy,
+			failures: 1,
+			err:      syscall.EIO,
+			call:     getProximity,
+			wantErr:  true,
+		},
+		{
+			name:     "retries disabled",
+			failures: 1,
+			err:      syscall.EREMOTEIO,
+			call:     getProximity,
+			wantErr:  true,
+		},
+		{
+			name:     "single attempt",
+			policy:   &singlePolicy,
+			failures: 1,
+			err:      syscall.EREMOTEIO,
+			call:     getProximity,
+			wantErr:  true,
+			want:     vcnl.RetryStats{Exhausted: 1},
+		},
+		{
+			// a failed INT_FLAG read may have cleared the flags, so it is
+			// never retried
+			name:     "INT_FLAG read",
+			policy:   &defaultPolicy,
+			failures: 1,
+			err:      syscall.EREMOTEIO,
+			call: func(s *vcnl.Sensor) error {
+				_, err := s.ReadInterrupts()
+				return err
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			s, dev := connect(t, vcnl.VCNL4040)
+
+			if tt.policy != nil {
+				s.SetRetryPolicy(*tt.policy)
+			}
+
+			dev.ClearTransactions()
+			dev.FailNext(tt.failures, tt.err)
+
+			err := tt.call(s)
+
+			if tt.wantErr {
+				if !errors.Is(err, tt.err) {
+					t.Errorf("expected %v, got %v", tt.err, err)
+				}
+
+				var busErr *vcnl.BusError
+
+				if !errors.As(err, &busErr) {
+					t.Errorf("expected a BusError, got %T", err)
+				}
+
+			} else if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+
+			if got := s.GetRetryStats(); got != tt.want {
+				t.Errorf("got stats %+v, want %+v", got, tt.want)
+			}
+
+			s.ResetRetryStats()
+
+			if got := s.GetRetryStats(); got != (vcnl.RetryStats{}) {
+				t.Errorf("stats %+v after reset", got)
+			}
+		})
+	}
+}
+
+func TestRetryINTFlagReadOnce(t *testing.T) {
+
+	s, dev := connect(t, vcnl.VCNL4040)
+	s.SetRetryPolicy(vcnl.DefaultRetryPolicy())
+
+	cc := vcnl.CommandCodes4040()
+	r := vcnl.Registers4040()
+
+	dev.RaiseInterrupt(r.INT_FLAG_CLOSE)
+	dev.FailNext(1, syscall.EREMOTEIO)
+
+	if _, err := s.ReadInterrupts(); !errors.Is(err, syscall.EREMOTEIO) {
+		t.Fatalf("expected EREMOTEIO, got %v", err)
+	}
+
+	// the failed read did not reach the device, so the flag is still set
+	// for the next read rather than consumed by a retry
+	flags, err := s.ReadInterrupts()
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !flags.Has(vcnl.FlagClose) {
+		t.Errorf("close flag not set, got %v", flags)
+	}
+
+	reads := 0
+
+	for _, tr := range dev.Transactions() {
+		if !tr.Write && tr.Cmd == cc.INT_FLAG {
+			reads++
+		}
+	}
+
+	if reads != 1 {
+		t.Errorf("INT_FLAG read %d times by the device, want 1", reads)
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+
+	s, dev := connect(t, vcnl.VCNL4040)
+
+	s.SetRetryPolicy(vcnl.RetryPolicy{
+		Attempts:   4,
+		Backoff:    5 * time.Millisecond,
+		MaxBackoff: 8 * time.Millisecond,
+		Retryable:  []syscall.Errno{syscall.EREMOTEIO},
+	})
+
+	dev.FailNext(3, syscall.EREMOTEIO)
+
+	start := time.Now()
+
+	if _, err := s.GetProximity(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// 5ms, then doubled to 10ms and limited to 8ms twice
+	if elapsed, want := time.Since(start), 21*time.Millisecond; elapsed < want {
+		t.Errorf("retries took %v, want at least %v", elapsed, want)
+	}
+
+	if got, want := s.GetRetryStats(), (vcnl.RetryStats{Retries: 3, Recovered: 1}); got != want {
+		t.Errorf("got stats %+v, want %+v", got, want)
+	}
+}
+
+// getProximity reads the proximity value discarding it
+func getProximity(s *vcnl.Sensor) error {
+	_, err := s.GetProximity()
+	return err
+}
@@ -0,0 +1,14 @@
+//go:build !linux
+// +build !linux
+
+package vcnl40xx
+
+import (
+	"syscall"
+)
+
+// defaultRetryable returns the errnos retried by DefaultRetryPolicy,
+// EREMOTEIO is only defined on Linux
+func defaultRetryable() []syscall.Errno {
+	return []syscall.Errno{syscall.ETIMEDOUT}
+}
@@ -0,0 +1,110 @@
+package vcnl40xx
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// runner is the background loop shared by the GestureEngine, HealthMonitor,
+// Sampler and EventSubscriber.  It calls tick at a fixed interval until its
+// context is done, it is closed or tick returns an error, then calls finish
+// to restore any sensor settings changed on starting and close the delivery
+// channel.
+type runner struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+	mu     sync.Mutex
+	// err is the error returned by tick which stopped the loop
+	err error
+	// finishErr is the error returned by finish
+	finishErr error
+}
+
+// start runs the loop in a new goroutine
+func (r *runner) start(ctx context.Context, interval time.Duration,
+	tick func(ctx context.Context) error, finish func() error) {
+
+	ctx, r.cancel = context.WithCancel(ctx)
+	r.done = make(chan struct{})
+
+	go func() {
+
+		defer close(r.done)
+
+		err := r.loop(ctx, interval, tick)
+		finishErr := finish()
+
+		// a sensor closed underneath the loop has nothing left to restore
+		if errors.Is(finishErr, ErrNotConnected) {
+			finishErr = nil
+		}
+
+		r.mu.Lock()
+		r.err, r.finishErr = err, finishErr
+		r.mu.Unlock()
+	}()
+}
+
+// loop calls tick at each interval, it returns nil if stopped by the context
+// or the Sensor being closed
+func (r *runner) loop(ctx context.Context, interval time.Duration,
+	tick func(ctx context.Context) error) error {
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		if err := tick(ctx); err != nil {
+			if stopping(ctx, err) {
+				return nil
+			}
+
+			return err
+		}
+	}
+}
+
+// stopping reports whether err from a tick is due to ctx being done or the
+// Sensor being closed, rather than a failed measurement
+func stopping(ctx context.Context, err error) bool {
+	return ctx.Err() != nil || errors.Is(err, ErrNotConnected)
+}
+
+// Close stops the background loop and waits for it to exit.  It returns the
+// error from restoring the sensor settings changed on starting, if any.
+func (r *runner) Close() error {
+
+	r.cancel()
+	<-r.done
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.finishErr
+}
+
+// Done returns a channel which is closed once the background loop has
+// stopped and the sensor settings have been restored
+func (r *runner) Done() <-chan struct{} {
+	return r.done
+}
+
+// Err returns the error which stopped the background loop.  It does not
+// wait, nil is returned while running or if the loop was stopped by its
+// context, Close or the Sensor being closed.  Use Done to wait for the loop
+// to stop.
+func (r *runner) Err() error {
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.err
+}
@@ -0,0 +1,81 @@
+package vcnl40xx
+
+import (
+	"fmt"
+)
+
+// AmbientRange defines the dynamic range multiplier of the ALS_HD and ALS_NS
+// settings on the VCNL4030 and VCNL4035.  Each x2 setting doubles the lux
+// measurable before the ambient value saturates, at half the resolution.
+type AmbientRange uint8
+
+const (
+	AmbientRange1 AmbientRange = 1
+	AmbientRange2 AmbientRange = 2
+)
+
+// SetAmbientHighDynamicRange sets the ALS_HD dynamic range on the VCNL4030
+// and VCNL4035.  Auto-ranging is turned off.
+func (s *Sensor) SetAmbientHighDynamicRange(val AmbientRange) error {
+
+	if s.model != VCNL4030 && s.model != VCNL4035 {
+		return ErrUnsupportedFeature
+	}
+
+	hdValue, ok := lookup(ambientHDOptions(s.reg), uint16(val))
+
+	if !ok {
+		return fmt.Errorf("unknown ambient range %d: %w", val, ErrInvalidArgument)
+	}
+
+	s.DisableAutoRange()
+
+	return s.bitMask(s.cc.ALS_CONF, LOWER, s.reg.ALS_HD_MASK, hdValue)
+}
+
+// GetAmbientHighDynamicRange returns the ALS_HD dynamic range on the
+// VCNL4030 and VCNL4035
+func (s *Sensor) GetAmbientHighDynamicRange() (AmbientRange, error) {
+
+	if s.model != VCNL4030 && s.model != VCNL4035 {
+		return 0, ErrUnsupportedFeature
+	}
+
+	v, err := s.getField("ambient high dynamic range", s.cc.ALS_CONF, LOWER,
+		ambientHDOptions(s.reg), s.reg.ALS_HD_MASK)
+
+	return AmbientRange(v), err
+}
+
+// SetAmbientSensitivityRange sets the ALS_NS dynamic range on the VCNL4030
+// and VCNL4035.  Auto-ranging is turned off.
+func (s *Sensor) SetAmbientSensitivityRange(val AmbientRange) error {
+
+	if s.model != VCNL4030 && s.model != VCNL4035 {
+		return ErrUnsupportedFeature
+	}
+
+	nsValue, ok := lookup(ambientNSOptions(s.reg), uint16(val))
+
+	if !ok {
+		return fmt.Errorf("unknown ambient range %d: %w", val, ErrInvalidArgument)
+	}
+
+	s.DisableAutoRange()
+
+	return s.bitMask(s.cc.ALS_CONF2, UPPER, s.reg.ALS_NS_MASK, nsValue)
+}
+
+// GetAmbientSensitivityRange returns the ALS_NS dynamic range on the
+// VCNL4030 and VCNL4035
+func (s *Sensor) GetAmbientSensitivityRange() (AmbientRange, error) {
+
+	if s.model != VCNL4030 && s.model != VCNL4035 {
+		return 0, ErrUnsupportedFeature
+	}
+
+	v, err := s.getField("ambient sensitivity range", s.cc.ALS_CONF2, UPPER,
+		ambientNSOptions(s.reg), s.reg.ALS_NS_MASK)
+
+	return AmbientRange(v), err
+}
@@ -1,6 +1,7 @@
 package vcnl40xx
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/swdee/go-i2c"
@@ -21,6 +22,64 @@ const (
 	ProximityPersistance4 ProximityPersistance = 4
 )
 
+// LEDCurrent defines the IR LED sink current settings in mA
+type LEDCurrent uint8
+
+const (
+	LED50mA  LEDCurrent = 50
+	LED75mA  LEDCurrent = 75
+	LED100mA LEDCurrent = 100
+	LED120mA LEDCurrent = 120
+	LED140mA LEDCurrent = 140
+	LED160mA LEDCurrent = 160
+	LED180mA LEDCurrent = 180
+	LED200mA LEDCurrent = 200
+)
+
+// DutyCycle defines the IR LED duty ratio settings as 1/value
+type DutyCycle uint16
+
+const (
+	Duty40  DutyCycle = 40
+	Duty80  DutyCycle = 80
+	Duty160 DutyCycle = 160
+	Duty320 DutyCycle = 320
+)
+
+// ALSIntegrationTime defines the ambient light integration time settings in
+// milliseconds
+type ALSIntegrationTime uint16
+
+const (
+	// VCNL4040
+	ALSIT80ms  ALSIntegrationTime = 80
+	ALSIT160ms ALSIntegrationTime = 160
+	ALSIT320ms ALSIntegrationTime = 320
+	ALSIT640ms ALSIntegrationTime = 640
+
+	// VCNL4030 and VCNL4035
+	ALSIT50ms  ALSIntegrationTime = 50
+	ALSIT100ms ALSIntegrationTime = 100
+	ALSIT200ms ALSIntegrationTime = 200
+	ALSIT400ms ALSIntegrationTime = 400
+	ALSIT800ms ALSIntegrationTime = 800
+)
+
+// ProximityIT defines the proximity integration time settings, with values
+// in units of half T
+type ProximityIT uint8
+
+const (
+	ProximityIT1T  ProximityIT = 2
+	ProximityIT15T ProximityIT = 3 // 1.5T
+	ProximityIT2T  ProximityIT = 4
+	ProximityIT25T ProximityIT = 5 // 2.5T
+	ProximityIT3T  ProximityIT = 6
+	ProximityIT35T ProximityIT = 7 // 3.5T
+	ProximityIT4T  ProximityIT = 8
+	ProximityIT8T  ProximityIT = 16
+)
+
 // AmbientPersistance defines the ambient persistance types
 type AmbientPersistance uint8
 
@@ -41,7 +100,10 @@ const (
 	InterruptBoth    InterruptType = 4
 )
 
-// Sensor defines the sensor device
+// Sensor defines the sensor device.  A Sensor is safe for concurrent use by
+// multiple goroutines, register read-modify-write sequences are serialised.
+// Note that reading the interrupt flags clears them on the sensor, so only
+// one goroutine should poll ReadInterrupts.
 type Sensor struct {
 	// model defines the sensor model initialized
 	model Model
@@ -49,32 +111,83 @@ type Sensor struct {
 	cc CommandCodes
 	// reg are the register values for the sensor model
 	reg Registers
-	// i2c bus connection
-	i2c *i2c.Options
+	// bus is the I2C bus connection
+	bus Bus
+	// cache enables the configuration register shadow copy
+	cache bool
+	// shadow holds the last known contents of the configuration registers
+	shadow map[byte]uint16
+	// auto is the ambient light auto-ranging state
+	auto autoRange
+	// strict causes setters to reject unsupported values instead of rounding
+	strict bool
+	// powerDownOnClose shuts down the sensor functions before closing the bus
+	powerDownOnClose bool
+	// mu serialises bus transactions and register read-modify-write
+	// sequences, and guards the fields above
+	mu ctxMutex
+	// rangeMu guards the auto-ranging state, it is always acquired before mu
+	rangeMu ctxMutex
+	// pending is closed when a bus transaction abandoned on context
+	// cancellation completes
+	pending chan struct{}
+	// retry is the policy for retrying failed bus transactions
+	retry RetryPolicy
+	// retries counts retried bus transactions
+	retries retryCounter
 }
 
 // NewSensor returns a driver instance for the given sensor Model
 func NewSensor(m Model) (*Sensor, error) {
 
+	cc, reg, err := modelTables(m)
+
+	if err != nil {
+		return nil, err
+	}
+
 	s := &Sensor{
-		model: m,
+		model:  m,
+		cc:     cc,
+		reg:    reg,
+		shadow: make(map[byte]uint16),
 	}
 
+	return s, nil
+}
+
+// modelTables returns the command codes and register values for the given
+// sensor Model
+func modelTables(m Model) (CommandCodes, Registers, error) {
+
 	switch m {
 	case VCNL4040:
-		s.cc = CommandCodes4040()
-		s.reg = Registers4040()
+		return CommandCodes4040(), Registers4040(), nil
 
 	case VCNL4030:
-		s.cc = CommandCodes4030()
-		s.reg = Registers4030()
+		return CommandCodes4030(), Registers4030(), nil
 
 	case VCNL4035:
-		s.cc = CommandCodes4035()
-		s.reg = Registers4035()
+		return CommandCodes4035(), Registers4035(), nil
 
 	default:
-		return nil, fmt.Errorf("Unknown sensor model")
+		return CommandCodes{}, Registers{}, ErrUnsupportedModel
+	}
+}
+
+// NewSensorWithBus returns a driver instance for the given sensor Model
+// communicating over the provided Bus.  The sensor ID is checked to match
+// the model.
+func NewSensorWithBus(m Model, bus Bus) (*Sensor, error) {
+
+	s, err := NewSensor(m)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.ConnectBus(bus); err != nil {
+		return nil, err
 	}
 
 	return s, nil
@@ -89,66 +202,205 @@ func (s *Sensor) Connect(dev string, addr uint8) error {
 		return fmt.Errorf("i2c bus error: %w", err)
 	}
 
-	s.i2c = i2c
-
-	check := s.i2c.GetAddr()
+	check := i2c.GetAddr()
 
 	if check == 0 {
+		i2c.Close()
 		return fmt.Errorf("I2C device is not initiated")
 	}
 
-	id, err := s.GetID()
+	if err := s.ConnectBus(i2c); err != nil {
+		i2c.Close()
+		return err
+	}
+
+	return nil
+}
+
+// ConnectBus connects to the sensor device over an already opened Bus.  The
+// sensor ID is read over the new bus and it is only attached once the ID
+// matches the model.  If the Sensor is already connected the previous bus is
+// closed and replaced.
+func (s *Sensor) ConnectBus(bus Bus) error {
+
+	if bus == nil {
+		return fmt.Errorf("nil bus: %w", ErrInvalidArgument)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// the new bus may be the one an abandoned transaction is using, when
+	// replacing a wedged bus it is closed regardless
+	s.waitPending()
+
+	id, err := s.readID(bus)
 
 	if err != nil {
 		return fmt.Errorf("error getting sensor ID: %w", err)
 	}
 
 	if id != s.model.ID() {
-		return fmt.Errorf("unexpected sensor ID value: 0x%04X, wanted 0x%04X", id, s.model.ID())
+		return &IDMismatchError{Got: id, Want: s.model.ID()}
+	}
+
+	var closeErr error
+
+	if s.bus != nil && s.bus != bus {
+		closeErr = s.bus.Close()
+	}
+
+	s.bus = bus
+	s.shadow = make(map[byte]uint16)
+	s.pending = nil
+
+	if closeErr != nil {
+		return fmt.Errorf("error closing previous bus: %w", closeErr)
 	}
 
 	return nil
 }
 
-// Init initialises the sensor and puts it in default state with proximity
-// and ambient light sensors activated
-func (s *Sensor) Init() error {
+// readID reads the sensor ID over the given bus before it is attached to the
+// Sensor.  The caller must hold the lock.
+func (s *Sensor) readID(bus Bus) (uint8, error) {
+
+	readBuf := make([]byte, 2)
 
-	if err := s.SetLEDCurrent(200); err != nil {
-		return fmt.Errorf("error setting LED current: %w", err)
+	if _, _, err := bus.WriteThenReadBytes([]byte{s.cc.ID}, readBuf); err != nil {
+		return 0, &BusError{Op: "read", Cmd: s.cc.ID, Err: err}
 	}
 
-	if err := s.SetIRDutyCycle(40); err != nil {
-		return fmt.Errorf("error setting IR duty cycle: %w", err)
+	return readBuf[0], nil
+}
+
+// Close releases the bus connection to the sensor.  If EnablePowerDownOnClose
+// has been called the proximity, ambient and white channel functions are shut
+// down first.  The sensor can be connected again after closing.
+func (s *Sensor) Close() error {
+
+	// hold both locks for the whole sequence so no other goroutine can use
+	// the sensor between powering down and releasing the bus
+	s.rangeMu.Lock()
+	defer s.rangeMu.Unlock()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.bus == nil {
+		return ErrNotConnected
 	}
 
-	if err := s.SetProximityIntegrationTime(8); err != nil {
-		return fmt.Errorf("error setting proximity integration time: %w", err)
+	// an abandoned transaction may still be using the bus, if it does not
+	// complete the bus is wedged so closing is all that can be done
+	pendingErr := s.waitPending()
+
+	var powerErr error
+
+	if s.powerDownOnClose && pendingErr == nil {
+		powerErr = s.powerDown(context.Background())
 	}
 
-	if err := s.SetProximityResolution(16); err != nil {
-		return fmt.Errorf("error setting proximity resolution: %w", err)
+	err := s.bus.Close()
+
+	s.bus = nil
+	s.shadow = make(map[byte]uint16)
+	s.pending = nil
+	s.auto = autoRange{}
+
+	if pendingErr != nil {
+		return fmt.Errorf("error closing bus: %w", pendingErr)
 	}
 
-	if err := s.EnableSmartPersistance(); err != nil {
-		return fmt.Errorf("error enabling smart persistance: %w", err)
+	if powerErr != nil {
+		return fmt.Errorf("error powering down sensor: %w", powerErr)
 	}
 
-	if err := s.PowerOnProximity(); err != nil {
-		return fmt.Errorf("error powering on proximity function: %w", err)
+	return err
+}
+
+// EnablePowerDownOnClose causes Close to shut down the sensor functions to
+// minimise power consumption before releasing the bus
+func (s *Sensor) EnablePowerDownOnClose() {
+	s.mu.Lock()
+	s.powerDownOnClose = true
+	s.mu.Unlock()
+}
+
+// DisablePowerDownOnClose leaves the sensor running when Close is called,
+// this is the default
+func (s *Sensor) DisablePowerDownOnClose() {
+	s.mu.Lock()
+	s.powerDownOnClose = false
+	s.mu.Unlock()
+}
+
+// powerDown shuts down all the sensor functions.  The caller must hold the
+// lock.
+func (s *Sensor) powerDown(ctx context.Context) error {
+
+	if err := s.maskRegister(ctx, s.cc.PS_CONF1, LOWER, s.reg.PS_SD_MASK, s.reg.PS_SD_POWER_OFF); err != nil {
+		return err
+	}
+
+	if err := s.maskRegister(ctx, s.cc.ALS_CONF, LOWER, s.reg.ALS_SD_MASK, s.reg.ALS_SD_POWER_OFF); err != nil {
+		return err
+	}
+
+	if s.model == VCNL4030 || s.model == VCNL4035 {
+		return s.maskRegister(ctx, s.cc.ALS_CONF2, UPPER, s.reg.WHITE_SD_MASK, s.reg.WHITE_SD_POWER_OFF)
+	}
+
+	return nil
+}
+
+// Init initialises the sensor and puts it in default state with proximity
+// and ambient light sensors activated.  Settings not changed by Init, such
+// as the interrupt thresholds, are left as they are.  Auto-ranging is turned
+// off.
+func (s *Sensor) Init() error {
+	return s.InitContext(context.Background())
+}
+
+// InitContext is Init returning the context error if ctx is done before the
+// sensor is initialised
+func (s *Sensor) InitContext(ctx context.Context) error {
+
+	s.DisableAutoRange()
+
+	alsIT := s.reg.ALS_IT_80MS
+
+	if s.model == VCNL4030 || s.model == VCNL4035 {
+		alsIT = s.reg.ALS_IT_50MS
 	}
 
-	if err := s.SetAmbientIntegrationTime(80); err != nil {
-		return fmt.Errorf("error setting ambient integration time: %w", err)
+	type step struct {
+		desc          string
+		commandCode   byte
+		commandHeight bool
+		mask          byte
+		value         byte
 	}
 
-	if err := s.PowerOnAmbient(); err != nil {
-		return fmt.Errorf("error powering on ambient lighting function: %w", err)
+	steps := []step{
+		{"setting LED current", s.cc.PS_MS, UPPER, s.reg.LED_I_MASK, s.reg.LED_200MA},
+		{"setting IR duty cycle", s.cc.PS_CONF1, LOWER, s.reg.PS_DUTY_MASK, s.reg.PS_DUTY_40},
+		{"setting proximity integration time", s.cc.PS_CONF1, LOWER, s.reg.PS_IT_MASK, s.reg.PS_IT_8T},
+		{"setting proximity resolution", s.cc.PS_CONF2, UPPER, s.reg.PS_HD_MASK, s.reg.PS_HD_16_BIT},
+		{"enabling smart persistance", s.cc.PS_CONF3, LOWER, s.reg.PS_SMART_PERS_MASK, s.reg.PS_SMART_PERS_ENABLE},
+		{"powering on proximity function", s.cc.PS_CONF1, LOWER, s.reg.PS_SD_MASK, s.reg.PS_SD_POWER_ON},
+		{"setting ambient integration time", s.cc.ALS_CONF, LOWER, s.reg.ALS_IT_MASK, alsIT},
+		{"powering on ambient lighting function", s.cc.ALS_CONF, LOWER, s.reg.ALS_SD_MASK, s.reg.ALS_SD_POWER_ON},
 	}
 
 	if s.model == VCNL4030 || s.model == VCNL4035 {
-		if err := s.PowerOnWhite(); err != nil {
-			return fmt.Errorf("error powering on white channel: %w", err)
+		steps = append(steps, step{"powering on white channel",
+			s.cc.ALS_CONF2, UPPER, s.reg.WHITE_SD_MASK, s.reg.WHITE_SD_POWER_ON})
+	}
+
+	for _, st := range steps {
+		if err := s.bitMaskContext(ctx, st.commandCode, st.commandHeight, st.mask, st.value); err != nil {
+			return fmt.Errorf("error %s: %w", st.desc, err)
 		}
 	}
 
@@ -158,7 +410,7 @@ func (s *Sensor) Init() error {
 // PowerOnWhite turns on the white channel sensor of the device
 func (s *Sensor) PowerOnWhite() error {
 	if s.model != VCNL4030 && s.model != VCNL4035 {
-		return fmt.Errorf("command not suport for given sensor model")
+		return ErrUnsupportedFeature
 	}
 	return s.bitMask(s.cc.ALS_CONF2, UPPER, s.reg.WHITE_SD_MASK, s.reg.WHITE_SD_POWER_ON)
 }
@@ -166,7 +418,7 @@ func (s *Sensor) PowerOnWhite() error {
 // PowerOffWhite turns off the white channel sensor of the device
 func (s *Sensor) PowerOffWhite() error {
 	if s.model != VCNL4030 && s.model != VCNL4035 {
-		return fmt.Errorf("command not suport for given sensor model")
+		return ErrUnsupportedFeature
 	}
 	return s.bitMask(s.cc.ALS_CONF2, UPPER, s.reg.WHITE_SD_MASK, s.reg.WHITE_SD_POWER_OFF)
 }
@@ -184,40 +436,19 @@ func (s *Sensor) PowerOffAmbient() error {
 // SetAmbientIntegrationTime sets the integration time for the ambient light
 // sensor in the number of milliseconds.
 // valid values for VCNL4040 are 80, 160, 320, or 640. for VCNL4030 are 50, 100,
-// 200, 400, or 800
+// 200, 400, or 800.  Auto-ranging is turned off.
 func (s *Sensor) SetAmbientIntegrationTime(timeValue uint16) error {
 
-	switch s.model {
-	case VCNL4040:
-		if timeValue >= 640 {
-			timeValue = uint16(s.reg.ALS_IT_640MS)
-		} else if timeValue >= 320 {
-			timeValue = uint16(s.reg.ALS_IT_320MS)
-		} else if timeValue >= 160 {
-			timeValue = uint16(s.reg.ALS_IT_160MS)
-		} else {
-			timeValue = uint16(s.reg.ALS_IT_80MS)
-		}
+	itValue, err := s.selectOption("ambient integration time",
+		ambientITOptions(s.model, s.reg), timeValue)
 
-	case VCNL4030, VCNL4035:
-		if timeValue >= 800 {
-			timeValue = uint16(s.reg.ALS_IT_800MS)
-		} else if timeValue >= 400 {
-			timeValue = uint16(s.reg.ALS_IT_400MS)
-		} else if timeValue >= 200 {
-			timeValue = uint16(s.reg.ALS_IT_200MS)
-		} else if timeValue >= 100 {
-			timeValue = uint16(s.reg.ALS_IT_100MS)
-		} else {
-			timeValue = uint16(s.reg.ALS_IT_50MS)
-		}
-
-	default:
-		// Optional: fail loudly for unsupported models
-		return fmt.Errorf("ambient integration time not supported for model %v", s.model)
+	if err != nil {
+		return err
 	}
 
-	return s.bitMask(s.cc.ALS_CONF, LOWER, s.reg.ALS_IT_MASK, byte(timeValue))
+	s.DisableAutoRange()
+
+	return s.bitMask(s.cc.ALS_CONF, LOWER, s.reg.ALS_IT_MASK, itValue)
 }
 
 // PowerOnProximity turns on the proximity sensor of the device
@@ -246,33 +477,44 @@ func (s *Sensor) DisableSmartPersistence() error {
 // valid values are 12 or 16.
 func (s *Sensor) SetProximityResolution(resolutionValue uint8) error {
 
-	if resolutionValue >= 16 {
-		resolutionValue = s.reg.PS_HD_16_BIT
-	} else {
-		resolutionValue = s.reg.PS_HD_12_BIT
+	hdValue, err := s.selectOption("proximity resolution",
+		proximityResolutionOptions(s.reg), uint16(resolutionValue))
+
+	if err != nil {
+		return err
 	}
 
-	return s.bitMask(s.cc.PS_CONF2, UPPER, s.reg.PS_HD_MASK, resolutionValue)
+	return s.bitMask(s.cc.PS_CONF2, UPPER, s.reg.PS_HD_MASK, hdValue)
 }
 
 // SetProximityIntegrationTime sets the integration time for the proximity sensor
 // which represents the duration of the energy being received. valid values
-// are 1, 2, 3, 4, or 8.
+// are 1, 2, 3, 4, or 8.  Use SetProximityIT to select the 1.5T, 2.5T and 3.5T
+// settings.
 func (s *Sensor) SetProximityIntegrationTime(timeValue uint8) error {
 
-	if timeValue >= 8 {
-		timeValue = s.reg.PS_IT_8T
-	} else if timeValue >= 4 {
-		timeValue = s.reg.PS_IT_4T
-	} else if timeValue >= 3 {
-		timeValue = s.reg.PS_IT_3T
-	} else if timeValue >= 2 {
-		timeValue = s.reg.PS_IT_2T
-	} else {
-		timeValue = s.reg.PS_IT_1T
+	itValue, err := s.selectOption("proximity integration time",
+		proximityITWholeOptions(s.reg), uint16(timeValue))
+
+	if err != nil {
+		return err
+	}
+
+	return s.bitMask(s.cc.PS_CONF1, LOWER, s.reg.PS_IT_MASK, itValue)
+}
+
+// SetProximityIT sets the integration time for the proximity sensor to any
+// of the eight supported settings
+func (s *Sensor) SetProximityIT(val ProximityIT) error {
+
+	itValue, err := exactOption("proximity integration time",
+		proximityITOptions(s.reg), uint16(val))
+
+	if err != nil {
+		return err
 	}
 
-	return s.bitMask(s.cc.PS_CONF1, LOWER, s.reg.PS_IT_MASK, timeValue)
+	return s.bitMask(s.cc.PS_CONF1, LOWER, s.reg.PS_IT_MASK, itValue)
 }
 
 // SetIRDutyCycle sets the duty cycle of the IR LED. The higher the duty
@@ -282,50 +524,95 @@ func (s *Sensor) SetProximityIntegrationTime(timeValue uint8) error {
 // valid values are 40, 80, 160, or 320.
 func (s *Sensor) SetIRDutyCycle(dutyValue uint16) error {
 
-	if dutyValue >= 320 {
-		dutyValue = uint16(s.reg.PS_DUTY_320)
-	} else if dutyValue >= 160 {
-		dutyValue = uint16(s.reg.PS_DUTY_160)
-	} else if dutyValue >= 80 {
-		dutyValue = uint16(s.reg.PS_DUTY_80)
-	} else {
-		dutyValue = uint16(s.reg.PS_DUTY_40)
+	duty, err := s.selectOption("IR duty cycle", irDutyOptions(s.reg), dutyValue)
+
+	if err != nil {
+		return err
 	}
 
-	return s.bitMask(s.cc.PS_CONF1, LOWER, s.reg.PS_DUTY_MASK, byte(dutyValue))
+	return s.bitMask(s.cc.PS_CONF1, LOWER, s.reg.PS_DUTY_MASK, duty)
 }
 
 // SetLEDCurrent sets the IR LED sink current to one of 8 settings. valid values
 // are 50, 75, 100, 120, 140, 160, 180, or 200 (maximum)
 func (s *Sensor) SetLEDCurrent(current uint8) error {
 
-	if current >= 200 {
-		current = s.reg.LED_200MA
-	} else if current >= 180 {
-		current = s.reg.LED_180MA
-	} else if current >= 160 {
-		current = s.reg.LED_160MA
-	} else if current >= 140 {
-		current = s.reg.LED_140MA
-	} else if current >= 120 {
-		current = s.reg.LED_120MA
-	} else if current >= 100 {
-		current = s.reg.LED_100MA
-	} else if current >= 75 {
-		current = s.reg.LED_75MA
-	} else {
-		current = s.reg.LED_50MA
+	ledValue, err := s.selectOption("LED current", ledCurrentOptions(s.reg), uint16(current))
+
+	if err != nil {
+		return err
 	}
 
-	return s.bitMask(s.cc.PS_MS, UPPER, s.reg.LED_I_MASK, current)
+	return s.bitMask(s.cc.PS_MS, UPPER, s.reg.LED_I_MASK, ledValue)
 }
 
-// readCommand writes command to sensor and reads the response
+// readCommand reads the 16-bit value at the given command code location,
+// served from the register cache when enabled
 func (s *Sensor) readCommand(commandCode byte) (uint16, error) {
+	return s.readCommandContext(context.Background(), commandCode)
+}
+
+// readCommandContext is readCommand returning early if ctx is done
+func (s *Sensor) readCommandContext(ctx context.Context, commandCode byte) (uint16, error) {
+
+	if err := s.mu.LockContext(ctx); err != nil {
+		return 0, err
+	}
+
+	defer s.mu.Unlock()
+
+	return s.readRegister(ctx, commandCode)
+}
+
+// readRegister is readCommand for callers already holding the lock
+func (s *Sensor) readRegister(ctx context.Context, commandCode byte) (uint16, error) {
+
+	cacheable := s.cacheable(commandCode)
+
+	if cacheable {
+		if value, ok := s.shadow[commandCode]; ok {
+			return value, nil
+		}
+	}
+
+	value, err := s.busRead(ctx, commandCode)
+
+	if err != nil {
+		return 0, err
+	}
+
+	if cacheable {
+		s.shadow[commandCode] = value
+	}
+
+	return value, nil
+}
+
+// busRead writes command to sensor and reads the response
+func (s *Sensor) busRead(ctx context.Context, commandCode byte) (uint16, error) {
 
 	readBuf := make([]byte, 2)
 
-	if _, _, err := s.i2c.WriteThenReadBytes([]byte{commandCode}, readBuf); err != nil {
+	read := func(bus Bus) error {
+
+		if _, _, err := bus.WriteThenReadBytes([]byte{commandCode}, readBuf); err != nil {
+			return &BusError{Op: "read", Cmd: commandCode, Err: err}
+		}
+
+		return nil
+	}
+
+	var err error
+
+	// reading INT_FLAG clears it, so a read which failed after the sensor
+	// responded can not be retried without losing the flags
+	if commandCode == s.cc.INT_FLAG {
+		err = s.transact(ctx, read)
+	} else {
+		err = s.transactRetry(ctx, read)
+	}
+
+	if err != nil {
 		return 0, err
 	}
 
@@ -338,38 +625,100 @@ func (s *Sensor) GetID() (uint8, error) {
 	return s.readCommandLower(s.cc.ID)
 }
 
-// writeCommand writes a 16-bit value to the given command code location
+// writeCommand writes a 16-bit value to the given command code location and
+// updates the register cache
 func (s *Sensor) writeCommand(commandCode byte, value uint16) error {
+	return s.writeCommandContext(context.Background(), commandCode, value)
+}
 
-	buf := []byte{commandCode, byte(value & 0xFF), byte(value >> 8)}
+// writeCommandContext is writeCommand returning early if ctx is done
+func (s *Sensor) writeCommandContext(ctx context.Context, commandCode byte, value uint16) error {
 
-	if _, err := s.i2c.WriteBytes(buf); err != nil {
+	if err := s.mu.LockContext(ctx); err != nil {
 		return err
 	}
 
+	defer s.mu.Unlock()
+
+	return s.writeRegister(ctx, commandCode, value)
+}
+
+// writeRegister is writeCommand for callers already holding the lock
+func (s *Sensor) writeRegister(ctx context.Context, commandCode byte, value uint16) error {
+
+	if err := s.busWrite(ctx, commandCode, value); err != nil {
+		// register state on the sensor is unknown so reload on next read
+		delete(s.shadow, commandCode)
+		return err
+	}
+
+	if s.cacheable(commandCode) {
+		if commandCode == s.cc.PS_CONF3 {
+			// the sensor clears the trigger bit once measurement is started
+			value &^= uint16(^s.reg.PS_TRIG_MASK)
+		}
+
+		s.shadow[commandCode] = value
+	}
+
 	return nil
 }
 
+// busWrite writes a 16-bit value to the sensor at the given command code
+func (s *Sensor) busWrite(ctx context.Context, commandCode byte, value uint16) error {
+
+	buf := []byte{commandCode, byte(value & 0xFF), byte(value >> 8)}
+
+	return s.transactRetry(ctx, func(bus Bus) error {
+
+		if _, err := bus.WriteBytes(buf); err != nil {
+			return &BusError{Op: "write", Cmd: commandCode, Err: err}
+		}
+
+		return nil
+	})
+}
+
 // bitMask reads a value from a register, masks it, then writes it back
 // commandHeight is used to select between the upper or lower byte of command register
 // Example: Write dutyValue into PS_CONF1, lower byte, using the Duty_Mask
 // bitMask( s.cc.PS_CONF1, LOWER,  s.reg.PS_DUTY_MASK, dutyValue)
 func (s *Sensor) bitMask(commandAddress byte, commandHeight bool,
 	mask byte, thing byte) error {
+	return s.bitMaskContext(context.Background(), commandAddress, commandHeight, mask, thing)
+}
 
-	var registerContents byte
-	var err error
+// bitMaskContext is bitMask returning early if ctx is done
+func (s *Sensor) bitMaskContext(ctx context.Context, commandAddress byte,
+	commandHeight bool, mask byte, thing byte) error {
 
-	if commandHeight == LOWER {
-		registerContents, err = s.readCommandLower(commandAddress)
-	} else {
-		registerContents, err = s.readCommandUpper(commandAddress)
+	// hold the lock so the read and write back are not interleaved with
+	// another goroutine changing the same register
+	if err := s.mu.LockContext(ctx); err != nil {
+		return err
 	}
 
+	defer s.mu.Unlock()
+
+	return s.maskRegister(ctx, commandAddress, commandHeight, mask, thing)
+}
+
+// maskRegister is bitMask for callers already holding the lock
+func (s *Sensor) maskRegister(ctx context.Context, commandAddress byte,
+	commandHeight bool, mask byte, thing byte) error {
+
+	commandValue, err := s.readRegister(ctx, commandAddress)
+
 	if err != nil {
 		return err
 	}
 
+	registerContents := byte(commandValue & 0xFF)
+
+	if commandHeight == UPPER {
+		registerContents = byte(commandValue >> 8)
+	}
+
 	// zero-out the portions of the register we're interested in
 	registerContents &= mask
 
@@ -378,9 +727,9 @@ func (s *Sensor) bitMask(commandAddress byte, commandHeight bool,
 
 	// change contents
 	if commandHeight == LOWER {
-		err = s.writeCommandLower(commandAddress, registerContents)
+		err = s.writeCommandLower(ctx, commandAddress, registerContents)
 	} else {
-		err = s.writeCommandUpper(commandAddress, registerContents)
+		err = s.writeCommandUpper(ctx, commandAddress, registerContents)
 	}
 
 	return err
@@ -411,10 +760,10 @@ func (s *Sensor) readCommandUpper(commandCode byte) (byte, error) {
 }
 
 // writeCommandLower writes to the lower byte without affecting the upper byte
-// for the given command code address
-func (s *Sensor) writeCommandLower(commandCode byte, newValue byte) error {
+// for the given command code address.  The caller must hold the lock.
+func (s *Sensor) writeCommandLower(ctx context.Context, commandCode byte, newValue byte) error {
 
-	commandValue, err := s.readCommand(commandCode)
+	commandValue, err := s.readRegister(ctx, commandCode)
 
 	if err != nil {
 		return err
@@ -423,14 +772,14 @@ func (s *Sensor) writeCommandLower(commandCode byte, newValue byte) error {
 	commandValue &= 0xFF00           // Remove lower 8 bits
 	commandValue |= uint16(newValue) // Mask in
 
-	return s.writeCommand(commandCode, commandValue)
+	return s.writeRegister(ctx, commandCode, commandValue)
 }
 
 // writeCommandUpper writew to the upper byte without affecting the lower byte
-// for the given command code address
-func (s *Sensor) writeCommandUpper(commandCode byte, newValue byte) error {
+// for the given command code address.  The caller must hold the lock.
+func (s *Sensor) writeCommandUpper(ctx context.Context, commandCode byte, newValue byte) error {
 
-	commandValue, err := s.readCommand(commandCode)
+	commandValue, err := s.readRegister(ctx, commandCode)
 
 	if err != nil {
 		return err
@@ -439,7 +788,7 @@ func (s *Sensor) writeCommandUpper(commandCode byte, newValue byte) error {
 	commandValue &= 0x00FF                // Remove upper 8 bits
 	commandValue |= uint16(newValue) << 8 // Mask in
 
-	return s.writeCommand(commandCode, commandValue)
+	return s.writeRegister(ctx, commandCode, commandValue)
 }
 
 // GetProximity reads the proximity value.  Values range from 0 to 65535
@@ -448,27 +797,38 @@ func (s *Sensor) GetProximity() (uint16, error) {
 	return s.readCommand(s.cc.PS_DATA)
 }
 
+// GetProximityContext is GetProximity returning the context error if ctx is
+// done before the value is read
+func (s *Sensor) GetProximityContext(ctx context.Context) (uint16, error) {
+	return s.readCommandContext(ctx, s.cc.PS_DATA)
+}
+
 // GetAmbient reads the ambient light value. Values range from 0 to 65535
 // where 0 is dark and 65535 is a bright light source.
 func (s *Sensor) GetAmbient() (uint16, error) {
 	return s.readCommand(s.cc.ALS_DATA)
 }
 
+// GetAmbientContext is GetAmbient returning the context error if ctx is done
+// before the value is read
+func (s *Sensor) GetAmbientContext(ctx context.Context) (uint16, error) {
+	return s.readCommandContext(ctx, s.cc.ALS_DATA)
+}
+
 // SetProximityInterruptPersistance sets the proximity interrupt persistance value
 // The PS persistence function (PS_PERS, 1, 2, 3, 4) helps to avoid
 // false trigger of the PS INT. It defines the amount of consecutive hits
 // needed in order for a PS interrupt event to be triggered.
 func (s *Sensor) SetProximityInterruptPersistance(val ProximityPersistance) error {
 
-	var persValue uint8
+	options := proximityPersistanceOptions(s.reg)
+	persValue, ok := lookup(options, uint16(val))
 
-	if val == ProximityPersistance1 {
-		persValue = s.reg.PS_PERS_1
-	} else if val == ProximityPersistance2 {
-		persValue = s.reg.PS_PERS_2
-	} else if val == ProximityPersistance3 {
-		persValue = s.reg.PS_PERS_3
-	} else {
+	if !ok && s.IsStrictMode() {
+		_, err := exactOption("proximity persistance", options, uint16(val))
+		return err
+
+	} else if !ok {
 		// ProximityPersistance4
 		persValue = s.reg.PS_PERS_4
 	}
@@ -483,15 +843,14 @@ func (s *Sensor) SetProximityInterruptPersistance(val ProximityPersistance) erro
 // valid values are  s.reg.ALS_PERS_[1,2,4,8]
 func (s *Sensor) SetAmbientInterruptPersistance(val AmbientPersistance) error {
 
-	var persValue uint8
+	options := ambientPersistanceOptions(s.reg)
+	persValue, ok := lookup(options, uint16(val))
 
-	if val == AmbientPersistance1 {
-		persValue = s.reg.ALS_PERS_1
-	} else if val == AmbientPersistance2 {
-		persValue = s.reg.ALS_PERS_2
-	} else if val == AmbientPersistance4 {
-		persValue = s.reg.ALS_PERS_4
-	} else {
+	if !ok && s.IsStrictMode() {
+		_, err := exactOption("ambient persistance", options, uint16(val))
+		return err
+
+	} else if !ok {
 		// AmbientPersistance8
 		persValue = s.reg.ALS_PERS_8
 	}
@@ -512,19 +871,10 @@ func (s *Sensor) DisableAmbientInterrupts() error {
 // SetProximityInterruptType sets the proximity interrupt type
 func (s *Sensor) SetProximityInterruptType(val InterruptType) error {
 
-	var interruptValue uint8
-
-	switch val {
-	case InterruptDisable:
-		interruptValue = s.reg.PS_INT_DISABLE
-	case InterruptClose:
-		interruptValue = s.reg.PS_INT_CLOSE
-	case InterruptAway:
-		interruptValue = s.reg.PS_INT_AWAY
-	case InterruptBoth:
-		interruptValue = s.reg.PS_INT_BOTH
-	default:
-		return fmt.Errorf("unknown interrupt type")
+	interruptValue, ok := lookup(interruptTypeOptions(s.reg), uint16(val))
+
+	if !ok {
+		return fmt.Errorf("unknown interrupt type %d: %w", val, ErrInvalidArgument)
 	}
 
 	return s.bitMask(s.cc.PS_CONF2, UPPER, s.reg.PS_INT_MASK, interruptValue)
@@ -547,7 +897,13 @@ func (s *Sensor) DisableActiveForceMode() error {
 // TakeSingleProximityMeasurement set trigger bit so sensor takes a force mode
 // measurement and returns to standby
 func (s *Sensor) TakeSingleProximityMeasurement() error {
-	return s.bitMask(s.cc.PS_CONF3, LOWER, s.reg.PS_TRIG_MASK, s.reg.PS_TRIG_TRIGGER)
+	return s.TakeSingleProximityMeasurementContext(context.Background())
+}
+
+// TakeSingleProximityMeasurementContext is TakeSingleProximityMeasurement
+// returning the context error if ctx is done before the trigger is written
+func (s *Sensor) TakeSingleProximityMeasurementContext(ctx context.Context) error {
+	return s.bitMaskContext(ctx, s.cc.PS_CONF3, LOWER, s.reg.PS_TRIG_MASK, s.reg.PS_TRIG_TRIGGER)
 }
 
 // EnableWhiteChannel enable the white measurement channel
@@ -566,11 +922,17 @@ func (s *Sensor) DisableWhiteChannel() error {
 // when the object moves away (value is below low threshold).
 // Register: PS_THDH / PS_THDL define where these threshold levels are set.
 func (s *Sensor) EnableProximityLogicMode() error {
+	if s.model == VCNL4030 || s.model == VCNL4035 {
+		return s.bitMask(s.cc.PS_CONF3, LOWER, s.reg.CONF3_PS_MS_MASK, s.reg.CONF3_PS_MS_OUTPUT_MODE)
+	}
 	return s.bitMask(s.cc.PS_MS, UPPER, s.reg.PS_MS_MASK, s.reg.PS_MS_ENABLE)
 }
 
 // DisableProximityLogicMode disable the proximity detection logic output mode
 func (s *Sensor) DisableProximityLogicMode() error {
+	if s.model == VCNL4030 || s.model == VCNL4035 {
+		return s.bitMask(s.cc.PS_CONF3, LOWER, s.reg.CONF3_PS_MS_MASK, s.reg.CONF3_PS_MS_NORMAL)
+	}
 	return s.bitMask(s.cc.PS_MS, UPPER, s.reg.PS_MS_MASK, s.reg.PS_MS_DISABLE)
 }
 
@@ -611,52 +973,64 @@ func (s *Sensor) GetWhite() (uint16, error) {
 	return s.readCommand(s.cc.WHITE_DATA)
 }
 
-// IsClose returns true if the proximity value rises above the upper threshold
+// GetWhiteContext is GetWhite returning the context error if ctx is done
+// before the value is read
+func (s *Sensor) GetWhiteContext(ctx context.Context) (uint16, error) {
+	return s.readCommandContext(ctx, s.cc.WHITE_DATA)
+}
+
+// IsClose returns true if the proximity value rises above the upper threshold.
+// Reading the interrupt status clears all flags, use ReadInterrupts to check
+// more than one event.
 func (s *Sensor) IsClose() (bool, error) {
 
-	interruptFlags, err := s.readCommandUpper(s.cc.INT_FLAG)
+	flags, err := s.ReadInterrupts()
 
 	if err != nil {
 		return false, err
 	}
 
-	return (interruptFlags & s.reg.INT_FLAG_CLOSE) != 0, nil
+	return flags.Has(FlagClose), nil
 }
 
-// IsAway returns true if the proximity value drops below the lower threshold
+// IsAway returns true if the proximity value drops below the lower threshold.
+// Reading the interrupt status clears all flags, use ReadInterrupts to check
+// more than one event.
 func (s *Sensor) IsAway() (bool, error) {
 
-	interruptFlags, err := s.readCommandUpper(s.cc.INT_FLAG)
+	flags, err := s.ReadInterrupts()
 
 	if err != nil {
 		return false, err
 	}
 
-	return (interruptFlags & s.reg.INT_FLAG_AWAY) != 0, nil
+	return flags.Has(FlagAway), nil
 }
 
 // IsLight returns true if the ambient light (ALS) value rises above the upper
-// threshold
+// threshold.  Reading the interrupt status clears all flags, use
+// ReadInterrupts to check more than one event.
 func (s *Sensor) IsLight() (bool, error) {
 
-	interruptFlags, err := s.readCommandUpper(s.cc.INT_FLAG)
+	flags, err := s.ReadInterrupts()
 
 	if err != nil {
 		return false, err
 	}
 
-	return (interruptFlags & s.reg.INT_FLAG_ALS_HIGH) != 0, nil
+	return flags.Has(FlagALSHigh), nil
 }
 
 // IsDark returns true if the ambient light (ALS) value drops below the lower
-// threshold
+// threshold.  Reading the interrupt status clears all flags, use
+// ReadInterrupts to check more than one event.
 func (s *Sensor) IsDark() (bool, error) {
 
-	interruptFlags, err := s.readCommandUpper(s.cc.INT_FLAG)
+	flags, err := s.ReadInterrupts()
 
 	if err != nil {
 		return false, err
 	}
 
-	return (interruptFlags & s.reg.INT_FLAG_ALS_LOW) != 0, nil
+	return flags.Has(FlagALSLow), nil
 }
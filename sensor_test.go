@@ -0,0 +1,1325 @@
+package vcnl40xx_test
+
+import (
+	"errors"
+	"testing"
+
+	vcnl "github.com/swdee/go-vcnl40xx"
+	"github.com/swdee/go-vcnl40xx/sim"
+)
+
+// seed is the contents the register under test is set to before a write, so
+// a change to any bit outside the field written can be detected
+const seed = 0x5A5A
+
+// fieldWrite is a field update expected to be made to one byte of a register
+type fieldWrite struct {
+	// cmd is the command code of the register
+	cmd byte
+	// lower is true if the field is in the lower byte of the register
+	lower bool
+	// mask keeps the bits outside the field, as in Registers
+	mask uint8
+	// bits are the field bits expected to be written
+	bits uint8
+}
+
+// lower returns a fieldWrite to the lower byte of a register
+func lower(cmd byte, mask, bits uint8) fieldWrite {
+	return fieldWrite{cmd: cmd, lower: true, mask: mask, bits: bits}
+}
+
+// upper returns a fieldWrite to the upper byte of a register
+func upper(cmd byte, mask, bits uint8) fieldWrite {
+	return fieldWrite{cmd: cmd, mask: mask, bits: bits}
+}
+
+// initial returns the register contents set before the write, with the field
+// cleared of the bits expected so the write must change it
+func (f fieldWrite) initial() uint16 {
+
+	field := ^f.mask
+
+	if f.lower {
+		return seed&0xFF00 | uint16(seed&0xFF&^field|^f.bits&field)
+	}
+
+	return seed&0x00FF | uint16(seed>>8&^field|^f.bits&field)<<8
+}
+
+// expected returns the register contents expected after the write, the
+// initial contents with only the field bits replaced
+func (f fieldWrite) expected() uint16 {
+
+	before := f.initial()
+
+	if f.lower {
+		return before&0xFF00 | uint16(uint8(before)&f.mask|f.bits)
+	}
+
+	return before&0x00FF | uint16(uint8(before>>8)&f.mask|f.bits)<<8
+}
+
+// supports returns true if m is one of the models, or models is empty
+func supports(models []vcnl.Model, m vcnl.Model) bool {
+
+	if len(models) == 0 {
+		return true
+	}
+
+	for _, model := range models {
+		if model == m {
+			return true
+		}
+	}
+
+	return false
+}
+
+// expectWrites checks the device received exactly the given write
+// transactions
+func expectWrites(t *testing.T, dev *sim.Device, want ...sim.Transaction) {
+
+	t.Helper()
+
+	got := dev.Writes()
+
+	if len(got) != len(want) {
+		t.Fatalf("got %d writes %+v, want %+v", len(got), got, want)
+	}
+
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("write %d is command code 0x%02X value 0x%04X, want command code 0x%02X value 0x%04X",
+				i, got[i].Cmd, got[i].Value, want[i].Cmd, want[i].Value)
+		}
+	}
+}
+
+var (
+	// newer are the models with the extended register set
+	newer = []vcnl.Model{vcnl.VCNL4030, vcnl.VCNL4035}
+	// only4040 is the VCNL4040 model
+	only4040 = []vcnl.Model{vcnl.VCNL4040}
+	// only4035 is the VCNL4035 model
+	only4035 = []vcnl.Model{vcnl.VCNL4035}
+)
+
+func TestFieldWrites(t *testing.T) {
+
+	tests := []struct {
+		name   string
+		models []vcnl.Model
+		call   func(s *vcnl.Sensor) error
+		want   func(m vcnl.Model, cc vcnl.CommandCodes, r vcnl.Registers) fieldWrite
+	}{
+		{
+			name:   "PowerOnWhite",
+			models: newer,
+			call:   (*vcnl.Sensor).PowerOnWhite,
+			want: func(m vcnl.Model, cc vcnl.CommandCodes, r vcnl.Registers) fieldWrite {
+				return upper(cc.ALS_CONF2, r.WHITE_SD_MASK, r.WHITE_SD_POWER_ON)
+			},
+		},
+		{
+			name:   "PowerOffWhite",
+			models: newer,
+			call:   (*vcnl.Sensor).PowerOffWhite,
+			want: func(m vcnl.Model, cc vcnl.CommandCodes, r vcnl.Registers) fieldWrite {
+				return upper(cc.ALS_CONF2, r.WHITE_SD_MASK, r.WHITE_SD_POWER_OFF)
+			},
+		},
+		{
+			name: "PowerOnAmbient",
+			call: (*vcnl.Sensor).PowerOnAmbient,
+			want: func(m vcnl.Model, cc vcnl.CommandCodes, r vcnl.Registers) fieldWrite {
+				return lower(cc.ALS_CONF, r.ALS_SD_MASK, r.ALS_SD_POWER_ON)
+			},
+		},
+		{
+			name: "PowerOffAmbient",
+			call: (*vcnl.Sensor).PowerOffAmbient,
+			want: func(m vcnl.Model, cc vcnl.CommandCodes, r vcnl.Registers) fieldWrite {
+				return lower(cc.ALS_CONF, r.ALS_SD_MASK, r.ALS_SD_POWER_OFF)
+			},
+		},
+		{
+			name: "SetAmbientIntegrationTime rounds down",
+			call: func(s *vcnl.Sensor) error { return s.SetAmbientIntegrationTime(300) },
+			want: func(m vcnl.Model, cc vcnl.CommandCodes, r vcnl.Registers) fieldWrite {
+				if m == vcnl.VCNL4040 {
+					return lower(cc.ALS_CONF, r.ALS_IT_MASK, r.ALS_IT_160MS)
+				}
+				return lower(cc.ALS_CONF, r.ALS_IT_MASK, r.ALS_IT_200MS)
+			},
+		},
+		{
+			name: "PowerOnProximity",
+			call: (*vcnl.Sensor).PowerOnProximity,
+			want: func(m vcnl.Model, cc vcnl.CommandCodes, r vcnl.Registers) fieldWrite {
+				return lower(cc.PS_CONF1, r.PS_SD_MASK, r.PS_SD_POWER_ON)
+			},
+		},
+		{
+			name: "PowerOffProximity",
+			call: (*vcnl.Sensor).PowerOffProximity,
+			want: func(m vcnl.Model, cc vcnl.CommandCodes, r vcnl.Registers) fieldWrite {
+				return lower(cc.PS_CONF1, r.PS_SD_MASK, r.PS_SD_POWER_OFF)
+			},
+		},
+		{
+			name: "EnableSmartPersistance",
+			call: (*vcnl.Sensor).EnableSmartPersistance,
+			want: func(m vcnl.Model, cc vcnl.CommandCodes, r vcnl.Registers) fieldWrite {
+				return lower(cc.PS_CONF3, r.PS_SMART_PERS_MASK, r.PS_SMART_PERS_ENABLE)
+			},
+		},
+		{
+			name: "DisableSmartPersistence",
+			call: (*vcnl.Sensor).DisableSmartPersistence,
+			want: func(m vcnl.Model, cc vcnl.CommandCodes, r vcnl.Registers) fieldWrite {
+				return lower(cc.PS_CONF3, r.PS_SMART_PERS_MASK, r.PS_SMART_PERS_DISABLE)
+			},
+		},
+		{
+			name: "SetProximityResolution",
+			call: func(s *vcnl.Sensor) error { return s.SetProximityResolution(16) },
+			want: func(m vcnl.Model, cc vcnl.CommandCodes, r vcnl.Registers) fieldWrite {
+				return upper(cc.PS_CONF2, r.PS_HD_MASK, r.PS_HD_16_BIT)
+			},
+		},
+		{
+			name: "SetProximityIntegrationTime rounds down",
+			call: func(s *vcnl.Sensor) error { return s.SetProximityIntegrationTime(5) },
+			want: func(m vcnl.Model, cc vcnl.CommandCodes, r vcnl.Registers) fieldWrite {
+				return lower(cc.PS_CONF1, r.PS_IT_MASK, r.PS_IT_4T)
+			},
+		},
+		{
+			name: "SetProximityIT",
+			call: func(s *vcnl.Sensor) error { return s.SetProximityIT(vcnl.ProximityIT35T) },
+			want: func(m vcnl.Model, cc vcnl.CommandCodes, r vcnl.Registers) fieldWrite {
+				return lower(cc.PS_CONF1, r.PS_IT_MASK, r.PS_IT_35T)
+			},
+		},
+		{
+			name: "SetIRDutyCycle rounds down",
+			call: func(s *vcnl.Sensor) error { return s.SetIRDutyCycle(100) },
+			want: func(m vcnl.Model, cc vcnl.CommandCodes, r vcnl.Registers) fieldWrite {
+				return lower(cc.PS_CONF1, r.PS_DUTY_MASK, r.PS_DUTY_80)
+			},
+		},
+		{
+			name: "SetLEDCurrent rounds down",
+			call: func(s *vcnl.Sensor) error { return s.SetLEDCurrent(130) },
+			want: func(m vcnl.Model, cc vcnl.CommandCodes, r vcnl.Registers) fieldWrite {
+				return upper(cc.PS_MS, r.LED_I_MASK, r.LED_120MA)
+			},
+		},
+		{
+			name: "SetProximityInterruptPersistance",
+			call: func(s *vcnl.Sensor) error {
+				return s.SetProximityInterruptPersistance(vcnl.ProximityPersistance3)
+			},
+			want: func(m vcnl.Model, cc vcnl.CommandCodes, r vcnl.Registers) fieldWrite {
+				return lower(cc.PS_CONF1, r.PS_PERS_MASK, r.PS_PERS_3)
+			},
+		},
+		{
+			name: "SetProximityInterruptPersistance unknown value",
+			call: func(s *vcnl.Sensor) error { return s.SetProximityInterruptPersistance(9) },
+			want: func(m vcnl.Model, cc vcnl.CommandCodes, r vcnl.Registers) fieldWrite {
+				return lower(cc.PS_CONF1, r.PS_PERS_MASK, r.PS_PERS_4)
+			},
+		},
+		{
+			name: "SetAmbientInterruptPersistance",
+			call: func(s *vcnl.Sensor) error {
+				return s.SetAmbientInterruptPersistance(vcnl.AmbientPersistance4)
+			},
+			want: func(m vcnl.Model, cc vcnl.CommandCodes, r vcnl.Registers) fieldWrite {
+				return lower(cc.ALS_CONF, r.ALS_PERS_MASK, r.ALS_PERS_4)
+			},
+		},
+		{
+			name: "SetAmbientInterruptPersistance unknown value",
+			call: func(s *vcnl.Sensor) error { return s.SetAmbientInterruptPersistance(3) },
+			want: func(m vcnl.Model, cc vcnl.CommandCodes, r vcnl.Registers) fieldWrite {
+				return lower(cc.ALS_CONF, r.ALS_PERS_MASK, r.ALS_PERS_8)
+			},
+		},
+		{
+			name: "EnableAmbientInterrupts",
+			call: (*vcnl.Sensor).EnableAmbientInterrupts,
+			want: func(m vcnl.Model, cc vcnl.CommandCodes, r vcnl.Registers) fieldWrite {
+				return lower(cc.ALS_CONF, r.ALS_INT_EN_MASK, r.ALS_INT_ENABLE)
+			},
+		},
+		{
+			name: "DisableAmbientInterrupts",
+			call: (*vcnl.Sensor).DisableAmbientInterrupts,
+			want: func(m vcnl.Model, cc vcnl.CommandCodes, r vcnl.Registers) fieldWrite {
+				return lower(cc.ALS_CONF, r.ALS_INT_EN_MASK, r.ALS_INT_DISABLE)
+			},
+		},
+		{
+			name: "SetProximityInterruptType",
+			call: func(s *vcnl.Sensor) error { return s.SetProximityInterruptType(vcnl.InterruptAway) },
+			want: func(m vcnl.Model, cc vcnl.CommandCodes, r vcnl.Registers) fieldWrite {
+				return upper(cc.PS_CONF2, r.PS_INT_MASK, r.PS_INT_AWAY)
+			},
+		},
+		{
+			name: "EnableActiveForceMode",
+			call: (*vcnl.Sensor).EnableActiveForceMode,
+			want: func(m vcnl.Model, cc vcnl.CommandCodes, r vcnl.Registers) fieldWrite {
+				return lower(cc.PS_CONF3, r.PS_AF_MASK, r.PS_AF_ENABLE)
+			},
+		},
+		{
+			name: "DisableActiveForceMode",
+			call: (*vcnl.Sensor).DisableActiveForceMode,
+			want: func(m vcnl.Model, cc vcnl.CommandCodes, r vcnl.Registers) fieldWrite {
+				return lower(cc.PS_CONF3, r.PS_AF_MASK, r.PS_AF_DISABLE)
+			},
+		},
+		{
+			name: "TakeSingleProximityMeasurement",
+			call: (*vcnl.Sensor).TakeSingleProximityMeasurement,
+			want: func(m vcnl.Model, cc vcnl.CommandCodes, r vcnl.Registers) fieldWrite {
+				return lower(cc.PS_CONF3, r.PS_TRIG_MASK, r.PS_TRIG_TRIGGER)
+			},
+		},
+		{
+			name:   "EnableWhiteChannel",
+			models: only4040,
+			call:   (*vcnl.Sensor).EnableWhiteChannel,
+			want: func(m vcnl.Model, cc vcnl.CommandCodes, r vcnl.Registers) fieldWrite {
+				return upper(cc.PS_MS, r.WHITE_EN_MASK, r.WHITE_ENABLE)
+			},
+		},
+		{
+			name:   "DisableWhiteChannel",
+			models: only4040,
+			call:   (*vcnl.Sensor).DisableWhiteChannel,
+			want: func(m vcnl.Model, cc vcnl.CommandCodes, r vcnl.Registers) fieldWrite {
+				return upper(cc.PS_MS, r.WHITE_EN_MASK, r.WHITE_DISABLE)
+			},
+		},
+		{
+			name: "EnableProximityLogicMode",
+			call: (*vcnl.Sensor).EnableProximityLogicMode,
+			want: func(m vcnl.Model, cc vcnl.CommandCodes, r vcnl.Registers) fieldWrite {
+				if m == vcnl.VCNL4040 {
+					return upper(cc.PS_MS, r.PS_MS_MASK, r.PS_MS_ENABLE)
+				}
+				return lower(cc.PS_CONF3, r.CONF3_PS_MS_MASK, r.CONF3_PS_MS_OUTPUT_MODE)
+			},
+		},
+		{
+			name: "DisableProximityLogicMode",
+			call: (*vcnl.Sensor).DisableProximityLogicMode,
+			want: func(m vcnl.Model, cc vcnl.CommandCodes, r vcnl.Registers) fieldWrite {
+				if m == vcnl.VCNL4040 {
+					return upper(cc.PS_MS, r.PS_MS_MASK, r.PS_MS_DISABLE)
+				}
+				return lower(cc.PS_CONF3, r.CONF3_PS_MS_MASK, r.CONF3_PS_MS_NORMAL)
+			},
+		},
+		{
+			name:   "SetProximityGain",
+			models: newer,
+			call:   func(s *vcnl.Sensor) error { return s.SetProximityGain(vcnl.ProximityGainSingle8) },
+			want: func(m vcnl.Model, cc vcnl.CommandCodes, r vcnl.Registers) fieldWrite {
+				return upper(cc.PS_CONF2, r.PS_GAIN_MASK, r.PS_GAIN_SINGLE_8)
+			},
+		},
+		{
+			name:   "SetProximityTwoStepRatio",
+			models: newer,
+			call:   func(s *vcnl.Sensor) error { return s.SetProximityTwoStepRatio(vcnl.ProximityTwoStep4) },
+			want: func(m vcnl.Model, cc vcnl.CommandCodes, r vcnl.Registers) fieldWrite {
+				return upper(cc.PS_CONF2, r.PS_NS_MASK, r.PS_NS_TWO_STEP_4)
+			},
+		},
+		{
+			name:   "EnableLowLEDCurrent",
+			models: newer,
+			call:   (*vcnl.Sensor).EnableLowLEDCurrent,
+			want: func(m vcnl.Model, cc vcnl.CommandCodes, r vcnl.Registers) fieldWrite {
+				return lower(cc.PS_CONF3, r.LED_I_LOW_MASK, r.LED_I_LOW_ENABLE)
+			},
+		},
+		{
+			name:   "DisableLowLEDCurrent",
+			models: newer,
+			call:   (*vcnl.Sensor).DisableLowLEDCurrent,
+			want: func(m vcnl.Model, cc vcnl.CommandCodes, r vcnl.Registers) fieldWrite {
+				return lower(cc.PS_CONF3, r.LED_I_LOW_MASK, r.LED_I_LOW_DISABLE)
+			},
+		},
+		{
+			name: "EnableSunlightCancellation",
+			call: (*vcnl.Sensor).EnableSunlightCancellation,
+			want: func(m vcnl.Model, cc vcnl.CommandCodes, r vcnl.Registers) fieldWrite {
+				return lower(cc.PS_CONF3, r.PS_SC_EN_MASK, r.PS_SC_EN_ENABLE)
+			},
+		},
+		{
+			name: "DisableSunlightCancellation",
+			call: (*vcnl.Sensor).DisableSunlightCancellation,
+			want: func(m vcnl.Model, cc vcnl.CommandCodes, r vcnl.Registers) fieldWrite {
+				return lower(cc.PS_CONF3, r.PS_SC_EN_MASK, r.PS_SC_EN_DISABLE)
+			},
+		},
+		{
+			name:   "SetSunlightCurrent",
+			models: newer,
+			call:   func(s *vcnl.Sensor) error { return s.SetSunlightCurrent(vcnl.SunlightCurrent4) },
+			want: func(m vcnl.Model, cc vcnl.CommandCodes, r vcnl.Registers) fieldWrite {
+				return upper(cc.PS_MS, r.PS_SC_CUR_MASK, r.PS_SC_CUR_4)
+			},
+		},
+		{
+			name:   "SetSunlightProtection",
+			models: newer,
+			call:   func(s *vcnl.Sensor) error { return s.SetSunlightProtection(vcnl.SunlightProtection15) },
+			want: func(m vcnl.Model, cc vcnl.CommandCodes, r vcnl.Registers) fieldWrite {
+				return upper(cc.PS_MS, r.PS_SP_MASK, r.PS_SP_15)
+			},
+		},
+		{
+			name:   "SetSunlightOutput",
+			models: newer,
+			call:   func(s *vcnl.Sensor) error { return s.SetSunlightOutput(vcnl.SunlightOutputFF) },
+			want: func(m vcnl.Model, cc vcnl.CommandCodes, r vcnl.Registers) fieldWrite {
+				return upper(cc.PS_MS, r.PS_SPO_MASK, r.PS_SPO_MODE_1)
+			},
+		},
+		{
+			name:   "SetAmbientHighDynamicRange",
+			models: newer,
+			call:   func(s *vcnl.Sensor) error { return s.SetAmbientHighDynamicRange(vcnl.AmbientRange2) },
+			want: func(m vcnl.Model, cc vcnl.CommandCodes, r vcnl.Registers) fieldWrite {
+				return lower(cc.ALS_CONF, r.ALS_HD_MASK, r.ALS_HD_2)
+			},
+		},
+		{
+			name:   "SetAmbientSensitivityRange",
+			models: newer,
+			call:   func(s *vcnl.Sensor) error { return s.SetAmbientSensitivityRange(vcnl.AmbientRange2) },
+			want: func(m vcnl.Model, cc vcnl.CommandCodes, r vcnl.Registers) fieldWrite {
+				return upper(cc.ALS_CONF2, r.ALS_NS_MASK, r.ALS_NS_2)
+			},
+		},
+		{
+			name:   "SetProximityMultiPulse",
+			models: only4040,
+			call:   func(s *vcnl.Sensor) error { return s.SetProximityMultiPulse(vcnl.ProximityMultiPulse4) },
+			want: func(m vcnl.Model, cc vcnl.CommandCodes, r vcnl.Registers) fieldWrite {
+				return lower(cc.PS_CONF3, r.PS_MPS_MASK, r.PS_MPS_4)
+			},
+		},
+		{
+			name: "SetLEDCurrent",
+			call: func(s *vcnl.Sensor) error { return s.SetLEDCurrent(uint8(vcnl.LED160mA)) },
+			want: func(m vcnl.Model, cc vcnl.CommandCodes, r vcnl.Registers) fieldWrite {
+				return upper(cc.PS_MS, r.LED_I_MASK, r.LED_160MA)
+			},
+		},
+		{
+			name: "SetIRDutyCycle",
+			call: func(s *vcnl.Sensor) error { return s.SetIRDutyCycle(uint16(vcnl.Duty160)) },
+			want: func(m vcnl.Model, cc vcnl.CommandCodes, r vcnl.Registers) fieldWrite {
+				return lower(cc.PS_CONF1, r.PS_DUTY_MASK, r.PS_DUTY_160)
+			},
+		},
+		{
+			name:   "SetAmbientIntegrationTime",
+			models: only4040,
+			call:   func(s *vcnl.Sensor) error { return s.SetAmbientIntegrationTime(uint16(vcnl.ALSIT320ms)) },
+			want: func(m vcnl.Model, cc vcnl.CommandCodes, r vcnl.Registers) fieldWrite {
+				return lower(cc.ALS_CONF, r.ALS_IT_MASK, r.ALS_IT_320MS)
+			},
+		},
+		{
+			name:   "SetAmbientIntegrationTime",
+			models: newer,
+			call:   func(s *vcnl.Sensor) error { return s.SetAmbientIntegrationTime(uint16(vcnl.ALSIT400ms)) },
+			want: func(m vcnl.Model, cc vcnl.CommandCodes, r vcnl.Registers) fieldWrite {
+				return lower(cc.ALS_CONF, r.ALS_IT_MASK, r.ALS_IT_400MS)
+			},
+		},
+		{
+			name:   "EnableGestureMode",
+			models: only4035,
+			call:   (*vcnl.Sensor).EnableGestureMode,
+			want: func(m vcnl.Model, cc vcnl.CommandCodes, r vcnl.Registers) fieldWrite {
+				return lower(cc.PS_CONF3, r.GESTURE_MODE_MASK, r.GESTURE_MODE_ENABLE)
+			},
+		},
+		{
+			name:   "DisableGestureMode",
+			models: only4035,
+			call:   (*vcnl.Sensor).DisableGestureMode,
+			want: func(m vcnl.Model, cc vcnl.CommandCodes, r vcnl.Registers) fieldWrite {
+				return lower(cc.PS_CONF3, r.GESTURE_MODE_MASK, r.GESTURE_MODE_DISABLE)
+			},
+		},
+		{
+			name:   "EnableGestureInterrupt",
+			models: only4035,
+			call:   (*vcnl.Sensor).EnableGestureInterrupt,
+			want: func(m vcnl.Model, cc vcnl.CommandCodes, r vcnl.Registers) fieldWrite {
+				return lower(cc.PS_CONF3, r.GESTURE_INT_EN_MASK, r.GESTURE_INT_ENABLE)
+			},
+		},
+		{
+			name:   "DisableGestureInterrupt",
+			models: only4035,
+			call:   (*vcnl.Sensor).DisableGestureInterrupt,
+			want: func(m vcnl.Model, cc vcnl.CommandCodes, r vcnl.Registers) fieldWrite {
+				return lower(cc.PS_CONF3, r.GESTURE_INT_EN_MASK, r.GESTURE_INT_DISABLE)
+			},
+		},
+	}
+
+	for _, tc := range models {
+		m := tc.model
+
+		t.Run(tc.name, func(t *testing.T) {
+			for _, tt := range tests {
+				if !supports(tt.models, m) {
+					continue
+				}
+
+				t.Run(tt.name, func(t *testing.T) {
+
+					s, dev := connect(t, m)
+					want := tt.want(m, commandCodes(m), registers(m))
+
+					dev.SetRegister(want.cmd, want.initial())
+
+					if err := tt.call(s); err != nil {
+						t.Fatalf("unexpected error: %v", err)
+					}
+
+					expectWrites(t, dev, sim.Transaction{Write: true, Cmd: want.cmd, Value: want.expected()})
+				})
+			}
+		})
+	}
+}
+
+func TestRegisterWrites(t *testing.T) {
+
+	tests := []struct {
+		name string
+		call func(s *vcnl.Sensor) error
+		cmd  func(cc vcnl.CommandCodes) byte
+	}{
+		{
+			name: "SetProximityCancellation",
+			call: func(s *vcnl.Sensor) error { return s.SetProximityCancellation(0x1234) },
+			cmd:  func(cc vcnl.CommandCodes) byte { return cc.PS_CANC },
+		},
+		{
+			name: "SetALSHighThreshold",
+			call: func(s *vcnl.Sensor) error { return s.SetALSHighThreshold(0x1234) },
+			cmd:  func(cc vcnl.CommandCodes) byte { return cc.ALS_THDH },
+		},
+		{
+			name: "SetALSLowThreshold",
+			call: func(s *vcnl.Sensor) error { return s.SetALSLowThreshold(0x1234) },
+			cmd:  func(cc vcnl.CommandCodes) byte { return cc.ALS_THDL },
+		},
+		{
+			name: "SetProximityHighThreshold",
+			call: func(s *vcnl.Sensor) error { return s.SetProximityHighThreshold(0x1234) },
+			cmd:  func(cc vcnl.CommandCodes) byte { return cc.PS_THDH },
+		},
+		{
+			name: "SetProximityLowThreshold",
+			call: func(s *vcnl.Sensor) error { return s.SetProximityLowThreshold(0x1234) },
+			cmd:  func(cc vcnl.CommandCodes) byte { return cc.PS_THDL },
+		},
+	}
+
+	for _, tc := range models {
+		m := tc.model
+
+		t.Run(tc.name, func(t *testing.T) {
+			for _, tt := range tests {
+				t.Run(tt.name, func(t *testing.T) {
+
+					s, dev := connect(t, m)
+
+					if err := tt.call(s); err != nil {
+						t.Fatalf("unexpected error: %v", err)
+					}
+
+					expectWrites(t, dev, sim.Transaction{Write: true, Cmd: tt.cmd(commandCodes(m)), Value: 0x1234})
+				})
+			}
+		})
+	}
+}
+
+func TestSettingsReadBack(t *testing.T) {
+
+	tests := []struct {
+		name   string
+		models []vcnl.Model
+		set    func(s *vcnl.Sensor) error
+		get    func(s *vcnl.Sensor) (interface{}, error)
+		want   interface{}
+	}{
+		{
+			name: "LEDCurrent",
+			set:  func(s *vcnl.Sensor) error { return s.SetLEDCurrent(130) },
+			get:  func(s *vcnl.Sensor) (interface{}, error) { return s.GetLEDCurrent() },
+			want: uint8(120),
+		},
+		{
+			name: "IRDutyCycle",
+			set:  func(s *vcnl.Sensor) error { return s.SetIRDutyCycle(160) },
+			get:  func(s *vcnl.Sensor) (interface{}, error) { return s.GetIRDutyCycle() },
+			want: uint16(160),
+		},
+		{
+			name: "ProximityIntegrationTime",
+			set:  func(s *vcnl.Sensor) error { return s.SetProximityIntegrationTime(3) },
+			get:  func(s *vcnl.Sensor) (interface{}, error) { return s.GetProximityIntegrationTime() },
+			want: uint8(3),
+		},
+		{
+			name: "ProximityIT",
+			set:  func(s *vcnl.Sensor) error { return s.SetProximityIT(vcnl.ProximityIT25T) },
+			get:  func(s *vcnl.Sensor) (interface{}, error) { return s.GetProximityIT() },
+			want: vcnl.ProximityIT25T,
+		},
+		{
+			name: "ProximityResolution",
+			set:  func(s *vcnl.Sensor) error { return s.SetProximityResolution(16) },
+			get:  func(s *vcnl.Sensor) (interface{}, error) { return s.GetProximityResolution() },
+			want: uint8(16),
+		},
+		{
+			name: "ProximityInterruptPersistance",
+			set: func(s *vcnl.Sensor) error {
+				return s.SetProximityInterruptPersistance(vcnl.ProximityPersistance2)
+			},
+			get:  func(s *vcnl.Sensor) (interface{}, error) { return s.GetProximityInterruptPersistance() },
+			want: vcnl.ProximityPersistance2,
+		},
+		{
+			name: "ProximityInterruptType",
+			set:  func(s *vcnl.Sensor) error { return s.SetProximityInterruptType(vcnl.InterruptBoth) },
+			get:  func(s *vcnl.Sensor) (interface{}, error) { return s.GetProximityInterruptType() },
+			want: vcnl.InterruptBoth,
+		},
+		{
+			name:   "AmbientIntegrationTime",
+			models: only4040,
+			set:    func(s *vcnl.Sensor) error { return s.SetAmbientIntegrationTime(640) },
+			get:    func(s *vcnl.Sensor) (interface{}, error) { return s.GetAmbientIntegrationTime() },
+			want:   uint16(640),
+		},
+		{
+			name:   "AmbientIntegrationTime",
+			models: newer,
+			set:    func(s *vcnl.Sensor) error { return s.SetAmbientIntegrationTime(800) },
+			get:    func(s *vcnl.Sensor) (interface{}, error) { return s.GetAmbientIntegrationTime() },
+			want:   uint16(800),
+		},
+		{
+			name: "AmbientInterruptPersistance",
+			set: func(s *vcnl.Sensor) error {
+				return s.SetAmbientInterruptPersistance(vcnl.AmbientPersistance2)
+			},
+			get:  func(s *vcnl.Sensor) (interface{}, error) { return s.GetAmbientInterruptPersistance() },
+			want: vcnl.AmbientPersistance2,
+		},
+		{
+			name: "ProximityCancellation",
+			set:  func(s *vcnl.Sensor) error { return s.SetProximityCancellation(42) },
+			get:  func(s *vcnl.Sensor) (interface{}, error) { return s.GetProximityCancellation() },
+			want: uint16(42),
+		},
+		{
+			name: "ALSHighThreshold",
+			set:  func(s *vcnl.Sensor) error { return s.SetALSHighThreshold(4000) },
+			get:  func(s *vcnl.Sensor) (interface{}, error) { return s.GetALSHighThreshold() },
+			want: uint16(4000),
+		},
+		{
+			name: "ALSLowThreshold",
+			set:  func(s *vcnl.Sensor) error { return s.SetALSLowThreshold(100) },
+			get:  func(s *vcnl.Sensor) (interface{}, error) { return s.GetALSLowThreshold() },
+			want: uint16(100),
+		},
+		{
+			name: "ProximityHighThreshold",
+			set:  func(s *vcnl.Sensor) error { return s.SetProximityHighThreshold(3000) },
+			get:  func(s *vcnl.Sensor) (interface{}, error) { return s.GetProximityHighThreshold() },
+			want: uint16(3000),
+		},
+		{
+			name: "ProximityLowThreshold",
+			set:  func(s *vcnl.Sensor) error { return s.SetProximityLowThreshold(200) },
+			get:  func(s *vcnl.Sensor) (interface{}, error) { return s.GetProximityLowThreshold() },
+			want: uint16(200),
+		},
+		{
+			name:   "ProximityGain",
+			models: newer,
+			set:    func(s *vcnl.Sensor) error { return s.SetProximityGain(vcnl.ProximityGainSingle1) },
+			get:    func(s *vcnl.Sensor) (interface{}, error) { return s.GetProximityGain() },
+			want:   vcnl.ProximityGainSingle1,
+		},
+		{
+			name:   "ProximityTwoStepRatio",
+			models: newer,
+			set:    func(s *vcnl.Sensor) error { return s.SetProximityTwoStepRatio(vcnl.ProximityTwoStep4) },
+			get:    func(s *vcnl.Sensor) (interface{}, error) { return s.GetProximityTwoStepRatio() },
+			want:   vcnl.ProximityTwoStep4,
+		},
+		{
+			name:   "LowLEDCurrent",
+			models: newer,
+			set:    (*vcnl.Sensor).EnableLowLEDCurrent,
+			get:    func(s *vcnl.Sensor) (interface{}, error) { return s.IsLowLEDCurrent() },
+			want:   true,
+		},
+		{
+			name: "ProximityPoweredOn",
+			set:  (*vcnl.Sensor).PowerOnProximity,
+			get:  func(s *vcnl.Sensor) (interface{}, error) { return s.IsProximityPoweredOn() },
+			want: true,
+		},
+		{
+			name: "ProximityPoweredOff",
+			set:  (*vcnl.Sensor).PowerOffProximity,
+			get:  func(s *vcnl.Sensor) (interface{}, error) { return s.IsProximityPoweredOn() },
+			want: false,
+		},
+		{
+			name: "AmbientPoweredOn",
+			set:  (*vcnl.Sensor).PowerOnAmbient,
+			get:  func(s *vcnl.Sensor) (interface{}, error) { return s.IsAmbientPoweredOn() },
+			want: true,
+		},
+		{
+			name: "AmbientPoweredOff",
+			set:  (*vcnl.Sensor).PowerOffAmbient,
+			get:  func(s *vcnl.Sensor) (interface{}, error) { return s.IsAmbientPoweredOn() },
+			want: false,
+		},
+		{
+			name:   "WhitePoweredOn",
+			models: newer,
+			set:    (*vcnl.Sensor).PowerOnWhite,
+			get:    func(s *vcnl.Sensor) (interface{}, error) { return s.IsWhitePoweredOn() },
+			want:   true,
+		},
+		{
+			name:   "WhitePoweredOff",
+			models: newer,
+			set:    (*vcnl.Sensor).PowerOffWhite,
+			get:    func(s *vcnl.Sensor) (interface{}, error) { return s.IsWhitePoweredOn() },
+			want:   false,
+		},
+		{
+			name: "WhiteChannelEnabled",
+			set:  (*vcnl.Sensor).EnableWhiteChannel,
+			get:  func(s *vcnl.Sensor) (interface{}, error) { return s.IsWhiteChannel() },
+			want: true,
+		},
+		{
+			name:   "WhiteChannelDisabled",
+			models: only4040,
+			set:    (*vcnl.Sensor).DisableWhiteChannel,
+			get:    func(s *vcnl.Sensor) (interface{}, error) { return s.IsWhiteChannel() },
+			want:   false,
+		},
+		{
+			name: "SmartPersistanceEnabled",
+			set:  (*vcnl.Sensor).EnableSmartPersistance,
+			get:  func(s *vcnl.Sensor) (interface{}, error) { return s.IsSmartPersistance() },
+			want: true,
+		},
+		{
+			name: "SmartPersistanceDisabled",
+			set:  (*vcnl.Sensor).DisableSmartPersistence,
+			get:  func(s *vcnl.Sensor) (interface{}, error) { return s.IsSmartPersistance() },
+			want: false,
+		},
+		{
+			name: "ActiveForceModeEnabled",
+			set:  (*vcnl.Sensor).EnableActiveForceMode,
+			get:  func(s *vcnl.Sensor) (interface{}, error) { return s.IsActiveForceMode() },
+			want: true,
+		},
+		{
+			name: "ActiveForceModeDisabled",
+			set:  (*vcnl.Sensor).DisableActiveForceMode,
+			get:  func(s *vcnl.Sensor) (interface{}, error) { return s.IsActiveForceMode() },
+			want: false,
+		},
+		{
+			name: "ProximityLogicModeEnabled",
+			set:  (*vcnl.Sensor).EnableProximityLogicMode,
+			get:  func(s *vcnl.Sensor) (interface{}, error) { return s.IsProximityLogicMode() },
+			want: true,
+		},
+		{
+			name: "ProximityLogicModeDisabled",
+			set:  (*vcnl.Sensor).DisableProximityLogicMode,
+			get:  func(s *vcnl.Sensor) (interface{}, error) { return s.IsProximityLogicMode() },
+			want: false,
+		},
+		{
+			name: "AmbientInterruptsEnabled",
+			set:  (*vcnl.Sensor).EnableAmbientInterrupts,
+			get:  func(s *vcnl.Sensor) (interface{}, error) { return s.IsAmbientInterrupts() },
+			want: true,
+		},
+		{
+			name: "AmbientInterruptsDisabled",
+			set:  (*vcnl.Sensor).DisableAmbientInterrupts,
+			get:  func(s *vcnl.Sensor) (interface{}, error) { return s.IsAmbientInterrupts() },
+			want: false,
+		},
+		{
+			name: "SunlightCancellation",
+			set:  (*vcnl.Sensor).EnableSunlightCancellation,
+			get:  func(s *vcnl.Sensor) (interface{}, error) { return s.IsSunlightCancellation() },
+			want: true,
+		},
+		{
+			name:   "SunlightCurrent",
+			models: newer,
+			set:    func(s *vcnl.Sensor) error { return s.SetSunlightCurrent(vcnl.SunlightCurrent8) },
+			get:    func(s *vcnl.Sensor) (interface{}, error) { return s.GetSunlightCurrent() },
+			want:   vcnl.SunlightCurrent8,
+		},
+		{
+			name:   "SunlightProtection",
+			models: newer,
+			set:    func(s *vcnl.Sensor) error { return s.SetSunlightProtection(vcnl.SunlightProtection15) },
+			get:    func(s *vcnl.Sensor) (interface{}, error) { return s.GetSunlightProtection() },
+			want:   vcnl.SunlightProtection15,
+		},
+		{
+			name:   "SunlightOutput",
+			models: newer,
+			set:    func(s *vcnl.Sensor) error { return s.SetSunlightOutput(vcnl.SunlightOutputFF) },
+			get:    func(s *vcnl.Sensor) (interface{}, error) { return s.GetSunlightOutput() },
+			want:   vcnl.SunlightOutputFF,
+		},
+		{
+			name:   "AmbientHighDynamicRange",
+			models: newer,
+			set:    func(s *vcnl.Sensor) error { return s.SetAmbientHighDynamicRange(vcnl.AmbientRange2) },
+			get:    func(s *vcnl.Sensor) (interface{}, error) { return s.GetAmbientHighDynamicRange() },
+			want:   vcnl.AmbientRange2,
+		},
+		{
+			name:   "AmbientSensitivityRange",
+			models: newer,
+			set:    func(s *vcnl.Sensor) error { return s.SetAmbientSensitivityRange(vcnl.AmbientRange2) },
+			get:    func(s *vcnl.Sensor) (interface{}, error) { return s.GetAmbientSensitivityRange() },
+			want:   vcnl.AmbientRange2,
+		},
+		{
+			name:   "ProximityMultiPulse",
+			models: only4040,
+			set:    func(s *vcnl.Sensor) error { return s.SetProximityMultiPulse(vcnl.ProximityMultiPulse8) },
+			get:    func(s *vcnl.Sensor) (interface{}, error) { return s.GetProximityMultiPulse() },
+			want:   vcnl.ProximityMultiPulse8,
+		},
+	}
+
+	for _, tc := range models {
+		m := tc.model
+
+		t.Run(tc.name, func(t *testing.T) {
+			for _, tt := range tests {
+				if !supports(tt.models, m) {
+					continue
+				}
+
+				t.Run(tt.name, func(t *testing.T) {
+
+					s, _ := connect(t, m)
+
+					if err := tt.set(s); err != nil {
+						t.Fatalf("error setting value: %v", err)
+					}
+
+					got, err := tt.get(s)
+
+					if err != nil {
+						t.Fatalf("error getting value: %v", err)
+					}
+
+					if got != tt.want {
+						t.Errorf("got %v, want %v", got, tt.want)
+					}
+				})
+			}
+		})
+	}
+}
+
+func TestStrictModeRejectsValues(t *testing.T) {
+
+	tests := []struct {
+		name string
+		call func(s *vcnl.Sensor) error
+	}{
+		{"SetAmbientIntegrationTime", func(s *vcnl.Sensor) error { return s.SetAmbientIntegrationTime(300) }},
+		{"SetProximityResolution", func(s *vcnl.Sensor) error { return s.SetProximityResolution(14) }},
+		{"SetProximityIntegrationTime", func(s *vcnl.Sensor) error { return s.SetProximityIntegrationTime(5) }},
+		{"SetIRDutyCycle", func(s *vcnl.Sensor) error { return s.SetIRDutyCycle(100) }},
+		{"SetLEDCurrent", func(s *vcnl.Sensor) error { return s.SetLEDCurrent(130) }},
+		{"SetProximityInterruptPersistance", func(s *vcnl.Sensor) error {
+			return s.SetProximityInterruptPersistance(9)
+		}},
+		{"SetAmbientInterruptPersistance", func(s *vcnl.Sensor) error {
+			return s.SetAmbientInterruptPersistance(3)
+		}},
+	}
+
+	for _, tc := range models {
+		m := tc.model
+
+		t.Run(tc.name, func(t *testing.T) {
+			for _, tt := range tests {
+				t.Run(tt.name, func(t *testing.T) {
+
+					s, dev := connect(t, m)
+					s.EnableStrictMode()
+
+					if err := tt.call(s); !errors.Is(err, vcnl.ErrInvalidArgument) {
+						t.Errorf("expected ErrInvalidArgument, got %v", err)
+					}
+
+					expectWrites(t, dev)
+				})
+			}
+		})
+	}
+}
+
+func TestInvalidValues(t *testing.T) {
+
+	s, dev := connect(t, vcnl.VCNL4040)
+
+	tests := []struct {
+		name string
+		call func() error
+	}{
+		{"SetProximityIT", func() error { return s.SetProximityIT(9) }},
+		{"SetProximityInterruptType", func() error { return s.SetProximityInterruptType(7) }},
+	}
+
+	for _, tt := range tests {
+		if err := tt.call(); !errors.Is(err, vcnl.ErrInvalidArgument) {
+			t.Errorf("%s: expected ErrInvalidArgument, got %v", tt.name, err)
+		}
+	}
+
+	expectWrites(t, dev)
+}
+
+func TestUnsupportedFeatures(t *testing.T) {
+
+	tests := []struct {
+		name   string
+		models []vcnl.Model
+		call   func(s *vcnl.Sensor) error
+	}{
+		{"PowerOnWhite", only4040, (*vcnl.Sensor).PowerOnWhite},
+		{"PowerOffWhite", only4040, (*vcnl.Sensor).PowerOffWhite},
+		{"SetProximityGain", only4040, func(s *vcnl.Sensor) error {
+			return s.SetProximityGain(vcnl.ProximityGainSingle8)
+		}},
+		{"SetProximityTwoStepRatio", only4040, func(s *vcnl.Sensor) error {
+			return s.SetProximityTwoStepRatio(vcnl.ProximityTwoStep4)
+		}},
+		{"EnableLowLEDCurrent", only4040, (*vcnl.Sensor).EnableLowLEDCurrent},
+		{"IsWhitePoweredOn", only4040, func(s *vcnl.Sensor) error {
+			_, err := s.IsWhitePoweredOn()
+			return err
+		}},
+		{"SetSunlightCurrent", only4040, func(s *vcnl.Sensor) error {
+			return s.SetSunlightCurrent(vcnl.SunlightCurrent4)
+		}},
+		{"SetAmbientHighDynamicRange", only4040, func(s *vcnl.Sensor) error {
+			return s.SetAmbientHighDynamicRange(vcnl.AmbientRange2)
+		}},
+		{"SetProximityMultiPulse", newer, func(s *vcnl.Sensor) error {
+			return s.SetProximityMultiPulse(vcnl.ProximityMultiPulse2)
+		}},
+		{"EnableGestureMode", []vcnl.Model{vcnl.VCNL4040, vcnl.VCNL4030}, (*vcnl.Sensor).EnableGestureMode},
+		{"GetProximityChannel", []vcnl.Model{vcnl.VCNL4040, vcnl.VCNL4030}, func(s *vcnl.Sensor) error {
+			_, err := s.GetProximityChannel(1)
+			return err
+		}},
+	}
+
+	for _, tc := range models {
+		m := tc.model
+
+		t.Run(tc.name, func(t *testing.T) {
+			for _, tt := range tests {
+				if !supports(tt.models, m) {
+					continue
+				}
+
+				t.Run(tt.name, func(t *testing.T) {
+
+					s, dev := connect(t, m)
+
+					if err := tt.call(s); !errors.Is(err, vcnl.ErrUnsupportedFeature) {
+						t.Errorf("expected ErrUnsupportedFeature, got %v", err)
+					}
+
+					expectWrites(t, dev)
+				})
+			}
+		})
+	}
+}
+
+func TestReadings(t *testing.T) {
+
+	for _, tc := range models {
+		m := tc.model
+
+		t.Run(tc.name, func(t *testing.T) {
+
+			s, dev := connect(t, m)
+
+			dev.SetProximity(1200)
+			dev.SetAmbient(300)
+			dev.SetWhite(450)
+
+			tests := []struct {
+				name string
+				read func() (uint16, error)
+				want uint16
+			}{
+				{"GetProximity", s.GetProximity, 1200},
+				{"GetAmbient", s.GetAmbient, 300},
+				{"GetWhite", s.GetWhite, 450},
+				{"GetID", func() (uint16, error) {
+					id, err := s.GetID()
+					return uint16(id), err
+				}, uint16(m.ID())},
+			}
+
+			for _, tt := range tests {
+
+				got, err := tt.read()
+
+				if err != nil {
+					t.Errorf("%s: unexpected error: %v", tt.name, err)
+				} else if got != tt.want {
+					t.Errorf("%s: got %d, want %d", tt.name, got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestInterruptFlags(t *testing.T) {
+
+	tests := []struct {
+		name  string
+		flag  func(r vcnl.Registers) uint8
+		check func(s *vcnl.Sensor) (bool, error)
+	}{
+		{"IsClose", func(r vcnl.Registers) uint8 { return r.INT_FLAG_CLOSE }, (*vcnl.Sensor).IsClose},
+		{"IsAway", func(r vcnl.Registers) uint8 { return r.INT_FLAG_AWAY }, (*vcnl.Sensor).IsAway},
+		{"IsLight", func(r vcnl.Registers) uint8 { return r.INT_FLAG_ALS_HIGH }, (*vcnl.Sensor).IsLight},
+		{"IsDark", func(r vcnl.Registers) uint8 { return r.INT_FLAG_ALS_LOW }, (*vcnl.Sensor).IsDark},
+	}
+
+	for _, tc := range models {
+		m := tc.model
+
+		t.Run(tc.name, func(t *testing.T) {
+			for _, tt := range tests {
+				t.Run(tt.name, func(t *testing.T) {
+
+					s, dev := connect(t, m)
+
+					dev.RaiseInterrupt(tt.flag(registers(m)))
+
+					if set, err := tt.check(s); err != nil || !set {
+						t.Fatalf("got %v, %v, want flag set", set, err)
+					}
+
+					// reading the flags clears them
+					if set, err := tt.check(s); err != nil || set {
+						t.Errorf("got %v, %v, want flag cleared", set, err)
+					}
+				})
+			}
+		})
+	}
+}
+
+func TestReadInterrupts(t *testing.T) {
+
+	s, dev := connect(t, vcnl.VCNL4040)
+	r := vcnl.Registers4040()
+
+	dev.RaiseInterrupt(r.INT_FLAG_CLOSE | r.INT_FLAG_ALS_LOW)
+
+	flags, err := s.ReadInterrupts()
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !flags.Has(vcnl.FlagClose|vcnl.FlagALSLow) || flags.Has(vcnl.FlagAway) {
+		t.Errorf("unexpected flags %v", flags)
+	}
+}
+
+func TestConnectBusIDMismatch(t *testing.T) {
+
+	for _, tc := range models {
+		m := tc.model
+
+		t.Run(tc.name, func(t *testing.T) {
+
+			dev, err := sim.New(m)
+
+			if err != nil {
+				t.Fatalf("error creating simulated device: %v", err)
+			}
+
+			id := commandCodes(m).ID
+			dev.SetRegister(id, dev.Register(id)&0xFF00|uint16(m.ID()+1))
+
+			s, err := vcnl.NewSensor(m)
+
+			if err != nil {
+				t.Fatalf("error creating sensor: %v", err)
+			}
+
+			err = s.ConnectBus(dev)
+
+			var mismatch *vcnl.IDMismatchError
+
+			if !errors.As(err, &mismatch) {
+				t.Fatalf("expected IDMismatchError, got %v", err)
+			}
+
+			if mismatch.Got != m.ID()+1 || mismatch.Want != m.ID() {
+				t.Errorf("unexpected mismatch %+v", mismatch)
+			}
+
+			if !errors.Is(s.Close(), vcnl.ErrNotConnected) {
+				t.Error("sensor connected with mismatching ID")
+			}
+		})
+	}
+}
+
+func TestConnectBusNil(t *testing.T) {
+
+	s, err := vcnl.NewSensor(vcnl.VCNL4040)
+
+	if err != nil {
+		t.Fatalf("error creating sensor: %v", err)
+	}
+
+	if err := s.ConnectBus(nil); !errors.Is(err, vcnl.ErrInvalidArgument) {
+		t.Errorf("expected ErrInvalidArgument, got %v", err)
+	}
+}
+
+func TestCloseClosesBus(t *testing.T) {
+
+	s, dev := connect(t, vcnl.VCNL4040)
+
+	if err := s.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !dev.Closed() {
+		t.Error("bus not closed")
+	}
+
+	if _, err := s.GetProximity(); !errors.Is(err, vcnl.ErrNotConnected) {
+		t.Errorf("expected ErrNotConnected, got %v", err)
+	}
+}
+
+func TestClosePowerDown(t *testing.T) {
+
+	for _, tc := range models {
+		m := tc.model
+
+		t.Run(tc.name, func(t *testing.T) {
+
+			s, dev := connect(t, m)
+			cc := commandCodes(m)
+			r := registers(m)
+
+			if err := s.Init(); err != nil {
+				t.Fatalf("error initialising sensor: %v", err)
+			}
+
+			s.EnablePowerDownOnClose()
+
+			if err := s.Close(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			ps1 := byte(dev.Register(cc.PS_CONF1))
+			als := dev.Register(cc.ALS_CONF)
+
+			if ps1&^r.PS_SD_MASK != r.PS_SD_POWER_OFF {
+				t.Errorf("PS_SD not set, PS_CONF1 is 0x%02X", ps1)
+			}
+
+			if byte(als)&^r.ALS_SD_MASK != r.ALS_SD_POWER_OFF {
+				t.Errorf("ALS_SD not set, ALS_CONF is 0x%04X", als)
+			}
+
+			if supports(newer, m) && byte(als>>8)&^r.WHITE_SD_MASK != r.WHITE_SD_POWER_OFF {
+				t.Errorf("WHITE_SD not set, ALS_CONF is 0x%04X", als)
+			}
+
+			if !dev.Closed() {
+				t.Error("bus not closed")
+			}
+		})
+	}
+}
+
+func TestCloseLeavesSensorRunning(t *testing.T) {
+
+	for _, tc := range models {
+		m := tc.model
+
+		t.Run(tc.name, func(t *testing.T) {
+
+			s, dev := connect(t, m)
+
+			if err := s.Init(); err != nil {
+				t.Fatalf("error initialising sensor: %v", err)
+			}
+
+			// power down is off by default, and when disabled again
+			s.EnablePowerDownOnClose()
+			s.DisablePowerDownOnClose()
+
+			dev.ClearTransactions()
+
+			if err := s.Close(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if writes := dev.Writes(); len(writes) != 0 {
+				t.Errorf("got writes %+v on close, want none", writes)
+			}
+		})
+	}
+}
+
+func TestInitWritesDefaults(t *testing.T) {
+
+	for _, tc := range models {
+		m := tc.model
+
+		t.Run(tc.name, func(t *testing.T) {
+
+			s, _ := connect(t, m)
+			s.EnableStrictMode()
+
+			if err := s.Init(); err != nil {
+				t.Fatalf("error initialising sensor: %v", err)
+			}
+
+			cfg, err := s.ReadConfig()
+
+			if err != nil {
+				t.Fatalf("error reading config: %v", err)
+			}
+
+			if want := vcnl.DefaultConfig(m); cfg != want {
+				t.Errorf("got config %+v, want %+v", cfg, want)
+			}
+		})
+	}
+}
+
+func TestMeasureProximity(t *testing.T) {
+
+	for _, tc := range models {
+		m := tc.model
+
+		t.Run(tc.name, func(t *testing.T) {
+
+			s, dev := connect(t, m)
+
+			dev.SetProximity(777)
+
+			if err := s.EnableActiveForceMode(); err != nil {
+				t.Fatalf("error enabling active force mode: %v", err)
+			}
+
+			value, err := s.MeasureProximity()
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if value != 777 {
+				t.Errorf("got %d, want 777", value)
+			}
+
+			if dev.Triggers() != 1 {
+				t.Errorf("got %d triggers, want 1", dev.Triggers())
+			}
+		})
+	}
+}
+
+func TestProximityChannels(t *testing.T) {
+
+	s, dev := connect(t, vcnl.VCNL4035)
+
+	for i, value := range []uint16{100, 200, 300} {
+		if err := dev.SetProximityChannel(i+1, value); err != nil {
+			t.Fatalf("error setting channel %d: %v", i+1, err)
+		}
+	}
+
+	data, err := s.GetProximityChannels()
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if data != [3]uint16{100, 200, 300} {
+		t.Errorf("got %v, want [100 200 300]", data)
+	}
+
+	value, err := s.GetProximityChannel(2)
+
+	if err != nil || value != 200 {
+		t.Errorf("got %d, %v, want 200", value, err)
+	}
+
+	if _, err := s.GetProximityChannel(4); !errors.Is(err, vcnl.ErrInvalidArgument) {
+		t.Errorf("expected ErrInvalidArgument, got %v", err)
+	}
+}
@@ -0,0 +1,274 @@
+package vcnl40xx
+
+import (
+	"fmt"
+	"strings"
+)
+
+// option maps a configuration setting value to its register bits
+type option struct {
+	// value of the setting in its physical units
+	value uint16
+	// bits are the register bits selecting the value
+	bits uint8
+}
+
+// roundDown returns the register bits for the largest option not greater
+// than the given value, or the smallest option if value is below all of
+// them.  options must be sorted in ascending order.
+func roundDown(options []option, value uint16) uint8 {
+
+	bits := options[0].bits
+
+	for _, o := range options {
+		if value >= o.value {
+			bits = o.bits
+		}
+	}
+
+	return bits
+}
+
+// selectOption returns the register bits for the given setting value.  In
+// strict mode the value must match one of the options exactly, otherwise it
+// is rounded down to the nearest option.
+func (s *Sensor) selectOption(name string, options []option, value uint16) (uint8, error) {
+
+	if !s.IsStrictMode() {
+		return roundDown(options, value), nil
+	}
+
+	return exactOption(name, options, value)
+}
+
+// exactOption returns the register bits for the option exactly matching
+// value, or an error listing the valid values
+func exactOption(name string, options []option, value uint16) (uint8, error) {
+
+	if bits, ok := lookup(options, value); ok {
+		return bits, nil
+	}
+
+	valid := make([]string, len(options))
+
+	for i, o := range options {
+		valid[i] = fmt.Sprintf("%d", o.value)
+	}
+
+	return 0, fmt.Errorf("unsupported %s %d, valid values are %s: %w",
+		name, value, strings.Join(valid, ", "), ErrInvalidArgument)
+}
+
+// lookup returns the register bits for the option exactly matching value
+func lookup(options []option, value uint16) (uint8, bool) {
+
+	for _, o := range options {
+		if o.value == value {
+			return o.bits, true
+		}
+	}
+
+	return 0, false
+}
+
+// decode returns the option value selected by the register contents using
+// the given field mask
+func decode(options []option, mask uint8, registerContents uint8) (uint16, bool) {
+
+	bits := registerContents &^ mask
+
+	for _, o := range options {
+		if o.bits == bits {
+			return o.value, true
+		}
+	}
+
+	return 0, false
+}
+
+// ledCurrentOptions returns the IR LED current settings in mA
+func ledCurrentOptions(r Registers) []option {
+	return []option{
+		{uint16(LED50mA), r.LED_50MA},
+		{uint16(LED75mA), r.LED_75MA},
+		{uint16(LED100mA), r.LED_100MA},
+		{uint16(LED120mA), r.LED_120MA},
+		{uint16(LED140mA), r.LED_140MA},
+		{uint16(LED160mA), r.LED_160MA},
+		{uint16(LED180mA), r.LED_180MA},
+		{uint16(LED200mA), r.LED_200MA},
+	}
+}
+
+// irDutyOptions returns the IR LED duty cycle settings as 1/value
+func irDutyOptions(r Registers) []option {
+	return []option{
+		{uint16(Duty40), r.PS_DUTY_40},
+		{uint16(Duty80), r.PS_DUTY_80},
+		{uint16(Duty160), r.PS_DUTY_160},
+		{uint16(Duty320), r.PS_DUTY_320},
+	}
+}
+
+// proximityITWholeOptions returns the whole step proximity integration time
+// settings in T, taken from the half T settings of proximityITOptions
+func proximityITWholeOptions(r Registers) []option {
+
+	var options []option
+
+	for _, o := range proximityITOptions(r) {
+		if o.value%2 == 0 {
+			options = append(options, option{o.value / 2, o.bits})
+		}
+	}
+
+	return options
+}
+
+// proximityITOptions returns the proximity integration time settings in
+// half T units
+func proximityITOptions(r Registers) []option {
+	return []option{
+		{uint16(ProximityIT1T), r.PS_IT_1T},
+		{uint16(ProximityIT15T), r.PS_IT_15T},
+		{uint16(ProximityIT2T), r.PS_IT_2T},
+		{uint16(ProximityIT25T), r.PS_IT_25T},
+		{uint16(ProximityIT3T), r.PS_IT_3T},
+		{uint16(ProximityIT35T), r.PS_IT_35T},
+		{uint16(ProximityIT4T), r.PS_IT_4T},
+		{uint16(ProximityIT8T), r.PS_IT_8T},
+	}
+}
+
+// proximityResolutionOptions returns the proximity output resolution
+// settings in bits
+func proximityResolutionOptions(r Registers) []option {
+	return []option{
+		{12, r.PS_HD_12_BIT},
+		{16, r.PS_HD_16_BIT},
+	}
+}
+
+// proximityPersistanceOptions returns the proximity interrupt persistance
+// settings
+func proximityPersistanceOptions(r Registers) []option {
+	return []option{
+		{uint16(ProximityPersistance1), r.PS_PERS_1},
+		{uint16(ProximityPersistance2), r.PS_PERS_2},
+		{uint16(ProximityPersistance3), r.PS_PERS_3},
+		{uint16(ProximityPersistance4), r.PS_PERS_4},
+	}
+}
+
+// ambientPersistanceOptions returns the ambient interrupt persistance
+// settings
+func ambientPersistanceOptions(r Registers) []option {
+	return []option{
+		{uint16(AmbientPersistance1), r.ALS_PERS_1},
+		{uint16(AmbientPersistance2), r.ALS_PERS_2},
+		{uint16(AmbientPersistance4), r.ALS_PERS_4},
+		{uint16(AmbientPersistance8), r.ALS_PERS_8},
+	}
+}
+
+// interruptTypeOptions returns the proximity interrupt type settings
+func interruptTypeOptions(r Registers) []option {
+	return []option{
+		{uint16(InterruptDisable), r.PS_INT_DISABLE},
+		{uint16(InterruptClose), r.PS_INT_CLOSE},
+		{uint16(InterruptAway), r.PS_INT_AWAY},
+		{uint16(InterruptBoth), r.PS_INT_BOTH},
+	}
+}
+
+// ambientITOptions returns the ambient integration time settings in
+// milliseconds for the given model
+func ambientITOptions(m Model, r Registers) []option {
+
+	if m == VCNL4040 {
+		return []option{
+			{uint16(ALSIT80ms), r.ALS_IT_80MS},
+			{uint16(ALSIT160ms), r.ALS_IT_160MS},
+			{uint16(ALSIT320ms), r.ALS_IT_320MS},
+			{uint16(ALSIT640ms), r.ALS_IT_640MS},
+		}
+	}
+
+	return []option{
+		{uint16(ALSIT50ms), r.ALS_IT_50MS},
+		{uint16(ALSIT100ms), r.ALS_IT_100MS},
+		{uint16(ALSIT200ms), r.ALS_IT_200MS},
+		{uint16(ALSIT400ms), r.ALS_IT_400MS},
+		{uint16(ALSIT800ms), r.ALS_IT_800MS},
+	}
+}
+
+// proximityGainOptions returns the proximity gain mode settings
+func proximityGainOptions(r Registers) []option {
+	return []option{
+		{uint16(ProximityGainTwoStep), r.PS_GAIN_TWO_STEP},
+		{uint16(ProximityGainSingle8), r.PS_GAIN_SINGLE_8},
+		{uint16(ProximityGainSingle1), r.PS_GAIN_SINGLE_1},
+	}
+}
+
+// proximityTwoStepOptions returns the proximity two-step gain ratio settings
+func proximityTwoStepOptions(r Registers) []option {
+	return []option{
+		{uint16(ProximityTwoStep1), r.PS_NS_TWO_STEP_1},
+		{uint16(ProximityTwoStep4), r.PS_NS_TWO_STEP_4},
+	}
+}
+
+// ambientHDOptions returns the ambient ALS_HD dynamic range settings
+func ambientHDOptions(r Registers) []option {
+	return []option{
+		{uint16(AmbientRange1), r.ALS_HD_1},
+		{uint16(AmbientRange2), r.ALS_HD_2},
+	}
+}
+
+// ambientNSOptions returns the ambient ALS_NS dynamic range settings
+func ambientNSOptions(r Registers) []option {
+	return []option{
+		{uint16(AmbientRange1), r.ALS_NS_1},
+		{uint16(AmbientRange2), r.ALS_NS_2},
+	}
+}
+
+// sunlightCurrentOptions returns the sunlight cancellation current settings
+func sunlightCurrentOptions(r Registers) []option {
+	return []option{
+		{uint16(SunlightCurrent1), r.PS_SC_CUR_1},
+		{uint16(SunlightCurrent2), r.PS_SC_CUR_2},
+		{uint16(SunlightCurrent4), r.PS_SC_CUR_4},
+		{uint16(SunlightCurrent8), r.PS_SC_CUR_8},
+	}
+}
+
+// sunlightProtectionOptions returns the sunlight protection capability
+// settings
+func sunlightProtectionOptions(r Registers) []option {
+	return []option{
+		{uint16(SunlightProtection1), r.PS_SP_1},
+		{uint16(SunlightProtection15), r.PS_SP_15},
+	}
+}
+
+// sunlightOutputOptions returns the sunlight protection output settings
+func sunlightOutputOptions(r Registers) []option {
+	return []option{
+		{uint16(SunlightOutput00), r.PS_SPO_MODE_0},
+		{uint16(SunlightOutputFF), r.PS_SPO_MODE_1},
+	}
+}
+
+// proximityMultiPulseOptions returns the proximity multi pulse settings
+func proximityMultiPulseOptions(r Registers) []option {
+	return []option{
+		{uint16(ProximityMultiPulse1), r.PS_MPS_1},
+		{uint16(ProximityMultiPulse2), r.PS_MPS_2},
+		{uint16(ProximityMultiPulse4), r.PS_MPS_4},
+		{uint16(ProximityMultiPulse8), r.PS_MPS_8},
+	}
+}
@@ -0,0 +1,409 @@
+/*
+Package sim provides an in-memory simulation of the Vishay VCNL40xx register
+file which can be used in place of a real I2C bus for testing code that uses
+the go-vcnl40xx driver without hardware.
+
+	dev, _ := sim.New(vcnl40xx.VCNL4040)
+	dev.SetProximity(10, 500, 3000)
+
+	sensor, _ := vcnl40xx.NewSensorWithBus(vcnl40xx.VCNL4040, dev)
+*/
+package sim
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/swdee/go-vcnl40xx"
+)
+
+// Transaction records a single bus transaction received by the Device
+type Transaction struct {
+	// Write is true for a write transaction and false for a read
+	Write bool
+	// Cmd is the command code addressed
+	Cmd byte
+	// Value is the 16-bit value written or returned
+	Value uint16
+}
+
+// Device is a simulated VCNL40xx sensor which implements the vcnl40xx.Bus
+// interface
+type Device struct {
+	mu sync.Mutex
+	// model defines the sensor model simulated
+	model vcnl40xx.Model
+	// cc are the command codes for the sensor model
+	cc vcnl40xx.CommandCodes
+	// reg are the register values for the sensor model
+	reg vcnl40xx.Registers
+	// regs holds the 16-bit contents of each register by command code
+	regs map[byte]uint16
+	// writable is the set of command codes the host can write to
+	writable map[byte]bool
+	// scripts holds queued data values to return for the data registers
+	scripts map[byte][]uint16
+	// triggers counts the number of active force mode triggers received
+	triggers int
+	// log records all transactions received
+	log []Transaction
+	// failures is the number of following transactions to fail with failErr
+	failures int
+	// failErr is the error returned by failed transactions
+	failErr error
+	// closed is set once the bus has been closed
+	closed bool
+}
+
+// New returns a simulated device for the given sensor Model in its power
+// on reset state
+func New(m vcnl40xx.Model) (*Device, error) {
+
+	d := &Device{
+		model:   m,
+		scripts: make(map[byte][]uint16),
+	}
+
+	switch m {
+	case vcnl40xx.VCNL4040:
+		d.cc = vcnl40xx.CommandCodes4040()
+		d.reg = vcnl40xx.Registers4040()
+
+	case vcnl40xx.VCNL4030:
+		d.cc = vcnl40xx.CommandCodes4030()
+		d.reg = vcnl40xx.Registers4030()
+
+	case vcnl40xx.VCNL4035:
+		d.cc = vcnl40xx.CommandCodes4035()
+		d.reg = vcnl40xx.Registers4035()
+
+	default:
+		return nil, vcnl40xx.ErrUnsupportedModel
+	}
+
+	d.writable = map[byte]bool{
+		d.cc.ALS_CONF: true,
+		d.cc.ALS_THDH: true,
+		d.cc.ALS_THDL: true,
+		d.cc.PS_CONF1: true,
+		d.cc.PS_CONF3: true,
+		d.cc.PS_CANC:  true,
+		d.cc.PS_THDL:  true,
+		d.cc.PS_THDH:  true,
+	}
+
+	d.Reset()
+
+	return d, nil
+}
+
+// Reset returns all registers to their power on default values, as happens
+// to the real device after a loss of power.  Scripted data values are kept.
+func (d *Device) Reset() {
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.regs = make(map[byte]uint16)
+
+	for cmd := range d.writable {
+		d.regs[cmd] = 0
+	}
+
+	d.regs[d.cc.PS_DATA] = 0
+	d.regs[d.cc.ALS_DATA] = 0
+	d.regs[d.cc.WHITE_DATA] = 0
+	d.regs[d.cc.INT_FLAG] = 0
+
+	// ALS and PS are shut down at power on
+	d.regs[d.cc.ALS_CONF] = uint16(^d.reg.ALS_SD_MASK)
+	d.regs[d.cc.PS_CONF1] = uint16(^d.reg.PS_SD_MASK)
+
+	switch d.model {
+	case vcnl40xx.VCNL4040:
+		d.regs[d.cc.ID] = 0x0100 | uint16(vcnl40xx.VCNL4040SensorID)
+
+	case vcnl40xx.VCNL4030:
+		d.regs[d.cc.ALS_CONF2] |= uint16(^d.reg.WHITE_SD_MASK) << 8
+		d.regs[d.cc.ID] = uint16(vcnl40xx.VCNL4030SensorID)
+
+	case vcnl40xx.VCNL4035:
+		d.regs[d.cc.ALS_CONF2] |= uint16(^d.reg.WHITE_SD_MASK) << 8
+		d.regs[d.cc.ID] = uint16(vcnl40xx.VCNL4035SensorID)
+		d.regs[d.cc.PS_DATA2] = 0
+		d.regs[d.cc.PS_DATA3] = 0
+	}
+}
+
+// WriteBytes handles a write transaction from the host.  The buffer must
+// contain the command code followed by the low and high data bytes.
+func (d *Device) WriteBytes(buf []byte) (int, error) {
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.closed {
+		return 0, fmt.Errorf("simulated bus is closed")
+	}
+
+	if err := d.fail(); err != nil {
+		return 0, err
+	}
+
+	if len(buf) != 3 {
+		return 0, fmt.Errorf("invalid write length %d", len(buf))
+	}
+
+	cmd := buf[0]
+
+	if _, ok := d.regs[cmd]; !ok {
+		return 0, fmt.Errorf("unknown command code 0x%02X", cmd)
+	}
+
+	value := uint16(buf[2])<<8 | uint16(buf[1])
+
+	d.log = append(d.log, Transaction{Write: true, Cmd: cmd, Value: value})
+
+	// writes to read only registers are ignored by the device
+	if !d.writable[cmd] {
+		return len(buf), nil
+	}
+
+	// PS_TRIG triggers a single measurement and then resets itself
+	if cmd == d.cc.PS_CONF3 && byte(value)&^d.reg.PS_TRIG_MASK != 0 {
+		d.triggers++
+		value &^= uint16(^d.reg.PS_TRIG_MASK)
+
+		// VCNL4035 flags the gesture measurement as complete straight away
+		// if the gesture interrupt is enabled
+		gestureMode := byte(value) &^ d.reg.GESTURE_MODE_MASK
+		gestureInt := byte(value) &^ d.reg.GESTURE_INT_EN_MASK
+		if d.reg.INT_FLAG_GESTURE != 0 && gestureMode == d.reg.GESTURE_MODE_ENABLE &&
+			gestureInt == d.reg.GESTURE_INT_ENABLE {
+			d.regs[d.cc.INT_FLAG] |= uint16(d.reg.INT_FLAG_GESTURE) << 8
+		}
+	}
+
+	d.regs[cmd] = value
+
+	return len(buf), nil
+}
+
+// WriteThenReadBytes handles a read transaction from the host.  The write
+// buffer must contain the command code and the read buffer receives the low
+// and high data bytes.
+func (d *Device) WriteThenReadBytes(writeBuf, readBuf []byte) (int, int, error) {
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.closed {
+		return 0, 0, fmt.Errorf("simulated bus is closed")
+	}
+
+	if err := d.fail(); err != nil {
+		return 0, 0, err
+	}
+
+	if len(writeBuf) != 1 || len(readBuf) != 2 {
+		return 0, 0, fmt.Errorf("invalid read transaction length %d/%d",
+			len(writeBuf), len(readBuf))
+	}
+
+	cmd := writeBuf[0]
+
+	if _, ok := d.regs[cmd]; !ok {
+		return 0, 0, fmt.Errorf("unknown command code 0x%02X", cmd)
+	}
+
+	if queue := d.scripts[cmd]; len(queue) > 0 {
+		d.regs[cmd] = queue[0]
+
+		// the last scripted value is held
+		if len(queue) > 1 {
+			d.scripts[cmd] = queue[1:]
+		}
+	}
+
+	value := d.regs[cmd]
+
+	d.log = append(d.log, Transaction{Cmd: cmd, Value: value})
+
+	// reading the interrupt flags clears them
+	if cmd == d.cc.INT_FLAG {
+		d.regs[cmd] = 0
+	}
+
+	readBuf[0] = byte(value & 0xFF)
+	readBuf[1] = byte(value >> 8)
+
+	return len(writeBuf), len(readBuf), nil
+}
+
+// Close closes the simulated bus, after which all transactions fail
+func (d *Device) Close() error {
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.closed = true
+
+	return nil
+}
+
+// Closed returns true if the simulated bus has been closed
+func (d *Device) Closed() bool {
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.closed
+}
+
+// Transactions returns a copy of all transactions received since the device
+// was created or ClearTransactions was called
+func (d *Device) Transactions() []Transaction {
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return append([]Transaction(nil), d.log...)
+}
+
+// Writes returns only the write transactions received since the device was
+// created or ClearTransactions was called
+func (d *Device) Writes() []Transaction {
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var writes []Transaction
+
+	for _, t := range d.log {
+		if t.Write {
+			writes = append(writes, t)
+		}
+	}
+
+	return writes
+}
+
+// ClearTransactions empties the transaction log
+func (d *Device) ClearTransactions() {
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.log = nil
+}
+
+// Register returns the contents of the register at the given command code
+// without any of the side effects of a bus read
+func (d *Device) Register(cmd byte) uint16 {
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.regs[cmd]
+}
+
+// SetRegister sets the contents of the register at the given command code,
+// including read only registers such as ID
+func (d *Device) SetRegister(cmd byte, value uint16) {
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.regs[cmd] = value
+}
+
+// SetProximity scripts the values returned by successive reads of PS_DATA.
+// The last value given is returned for all further reads.
+func (d *Device) SetProximity(values ...uint16) {
+	d.script(d.cc.PS_DATA, values)
+}
+
+// SetProximityChannel scripts the values returned by successive reads of
+// PS_DATA1, PS_DATA2 or PS_DATA3 on the VCNL4035.  valid channels are 1, 2,
+// or 3.
+func (d *Device) SetProximityChannel(channel int, values ...uint16) error {
+
+	if d.model != vcnl40xx.VCNL4035 {
+		return fmt.Errorf("proximity channels: %w", vcnl40xx.ErrUnsupportedFeature)
+	}
+
+	switch channel {
+	case 1:
+		d.script(d.cc.PS_DATA1, values)
+	case 2:
+		d.script(d.cc.PS_DATA2, values)
+	case 3:
+		d.script(d.cc.PS_DATA3, values)
+	default:
+		return fmt.Errorf("invalid proximity channel %d", channel)
+	}
+
+	return nil
+}
+
+// SetAmbient scripts the values returned by successive reads of ALS_DATA
+func (d *Device) SetAmbient(values ...uint16) {
+	d.script(d.cc.ALS_DATA, values)
+}
+
+// SetWhite scripts the values returned by successive reads of WHITE_DATA
+func (d *Device) SetWhite(values ...uint16) {
+	d.script(d.cc.WHITE_DATA, values)
+}
+
+// RaiseInterrupt sets the given flags in the upper byte of INT_FLAG, such as
+// Registers.INT_FLAG_CLOSE.  The flags remain set until INT_FLAG is read.
+func (d *Device) RaiseInterrupt(flags uint8) {
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.regs[d.cc.INT_FLAG] |= uint16(flags) << 8
+}
+
+// FailNext makes the next n bus transactions fail with err without reaching
+// the registers, to simulate a noisy or disturbed bus
+func (d *Device) FailNext(n int, err error) {
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.failures = n
+	d.failErr = err
+}
+
+// fail returns the error for a transaction to fail with, if any
+func (d *Device) fail() error {
+
+	if d.failures == 0 {
+		return nil
+	}
+
+	d.failures--
+
+	return d.failErr
+}
+
+// Triggers returns the number of single proximity measurements triggered by
+// the host via PS_TRIG
+func (d *Device) Triggers() int {
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.triggers
+}
+
+// script queues values to return for the given data register
+func (d *Device) script(cmd byte, values []uint16) {
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.scripts[cmd] = append([]uint16(nil), values...)
+}
@@ -0,0 +1,266 @@
+package vcnl40xx
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Reading is a single timestamped sample of the sensor channels
+type Reading struct {
+	// Time the sample was taken
+	Time time.Time
+	// Proximity is the proximity value
+	Proximity uint16
+	// Ambient is the ambient light value
+	Ambient uint16
+	// White is the white light value
+	White uint16
+}
+
+// DropPolicy defines what a Sampler does with a new Reading when the
+// receiver has not kept up and the channel buffer is full
+type DropPolicy uint8
+
+const (
+	// DropNewest discards the new Reading
+	DropNewest DropPolicy = 1
+	// DropOldest discards the oldest buffered Reading to make room for the
+	// new one
+	DropOldest DropPolicy = 2
+	// Block waits for the receiver, delaying the next sample
+	Block DropPolicy = 3
+)
+
+// StreamConfig defines the sampling of a Stream
+type StreamConfig struct {
+	// Interval between samples, if zero the interval is derived from the
+	// configured integration times, see SampleInterval
+	Interval time.Duration
+	// Buffer is the number of Readings buffered in the channel
+	Buffer int
+	// Drop is the policy when the buffer is full, defaults to DropNewest
+	Drop DropPolicy
+}
+
+// validate checks the settings can be used to sample the sensor
+func (c StreamConfig) validate() error {
+
+	if c.Interval < 0 {
+		return fmt.Errorf("stream interval %v must not be negative: %w", c.Interval, ErrInvalidArgument)
+	}
+
+	if c.Buffer < 0 {
+		return fmt.Errorf("stream buffer %d must not be negative: %w", c.Buffer, ErrInvalidArgument)
+	}
+
+	if c.Drop > Block {
+		return fmt.Errorf("unknown drop policy %d: %w", c.Drop, ErrInvalidArgument)
+	}
+
+	return nil
+}
+
+// Sampler reads the sensor in the background and delivers Readings on a
+// channel
+type Sampler struct {
+	runner
+	sensor   *Sensor
+	drop     DropPolicy
+	readings chan Reading
+	mu       sync.Mutex
+	dropped  uint64
+	// failed counts the samples skipped as a read failed
+	failed uint64
+	// readErr is the error of the most recent failed read
+	readErr error
+}
+
+// SampleInterval returns the time between new measurements being available,
+// which is the shorter of the proximity measurement period and the ambient
+// integration time
+func (s *Sensor) SampleInterval() (time.Duration, error) {
+
+	ps, err := s.ProximityPeriod()
+
+	if err != nil {
+		return 0, err
+	}
+
+	it, err := s.GetAmbientIntegrationTime()
+
+	if err != nil {
+		return 0, err
+	}
+
+	als := time.Duration(it) * time.Millisecond
+
+	if als < ps {
+		return als, nil
+	}
+
+	return ps, nil
+}
+
+// Stream starts sampling the proximity, ambient and white channels in the
+// background.  Sampling stops when ctx is done, the Sampler is closed or the
+// Sensor is closed.  A sample is skipped if reading it fails, see Failed.
+func (s *Sensor) Stream(ctx context.Context, cfg StreamConfig) (*Sampler, error) {
+
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+
+	interval := cfg.Interval
+
+	if interval == 0 {
+		var err error
+
+		if interval, err = s.SampleInterval(); err != nil {
+			return nil, err
+		}
+	}
+
+	drop := cfg.Drop
+
+	if drop == 0 {
+		drop = DropNewest
+	}
+
+	sp := &Sampler{
+		sensor:   s,
+		drop:     drop,
+		readings: make(chan Reading, cfg.Buffer),
+	}
+
+	sp.start(ctx, interval, sp.tick, sp.finish)
+
+	return sp, nil
+}
+
+// Readings returns the channel Readings are delivered on.  The channel is
+// closed when sampling stops.
+func (sp *Sampler) Readings() <-chan Reading {
+	return sp.readings
+}
+
+// Dropped returns the number of Readings discarded by the drop policy
+func (sp *Sampler) Dropped() uint64 {
+
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+
+	return sp.dropped
+}
+
+// Failed returns the number of samples skipped as reading the sensor failed
+// and the error of the most recent failure
+func (sp *Sampler) Failed() (uint64, error) {
+
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+
+	return sp.failed, sp.readErr
+}
+
+// tick takes a sample and delivers it according to the drop policy
+func (sp *Sampler) tick(ctx context.Context) error {
+
+	r, err := sp.read(ctx)
+
+	if err != nil {
+		if stopping(ctx, err) {
+			return err
+		}
+
+		sp.mu.Lock()
+		sp.failed++
+		sp.readErr = err
+		sp.mu.Unlock()
+
+		return nil
+	}
+
+	if !sp.deliver(ctx, r) {
+		return ctx.Err()
+	}
+
+	return nil
+}
+
+// finish closes the Readings channel
+func (sp *Sampler) finish() error {
+
+	close(sp.readings)
+
+	return nil
+}
+
+// read takes a sample of all channels
+func (sp *Sampler) read(ctx context.Context) (Reading, error) {
+
+	r := Reading{Time: time.Now()}
+
+	var err error
+
+	if r.Proximity, err = sp.sensor.GetProximityContext(ctx); err != nil {
+		return r, err
+	}
+
+	if r.Ambient, err = sp.sensor.GetAmbientContext(ctx); err != nil {
+		return r, err
+	}
+
+	if r.White, err = sp.sensor.GetWhiteContext(ctx); err != nil {
+		return r, err
+	}
+
+	return r, nil
+}
+
+// deliver sends the Reading according to the drop policy, it returns false
+// if ctx is done while blocked
+func (sp *Sampler) deliver(ctx context.Context, r Reading) bool {
+
+	select {
+	case sp.readings <- r:
+		return true
+	default:
+	}
+
+	switch sp.drop {
+	case Block:
+		select {
+		case sp.readings <- r:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+
+	case DropOldest:
+		select {
+		case <-sp.readings:
+			sp.countDrop()
+		default:
+		}
+
+		select {
+		case sp.readings <- r:
+		default:
+			sp.countDrop()
+		}
+
+	default:
+		sp.countDrop()
+	}
+
+	return true
+}
+
+// countDrop increments the dropped Readings counter
+func (sp *Sampler) countDrop() {
+	sp.mu.Lock()
+	sp.dropped++
+	sp.mu.Unlock()
+}
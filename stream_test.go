@@ -0,0 +1,184 @@
+package vcnl40xx_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	vcnl "github.com/swdee/go-vcnl40xx"
+)
+
+func TestStreamReadings(t *testing.T) {
+
+	for _, tc := range models {
+		t.Run(tc.name, func(t *testing.T) {
+
+			s, dev := connect(t, tc.model)
+
+			dev.SetProximity(1200)
+			dev.SetAmbient(300)
+			dev.SetWhite(450)
+
+			sp, err := s.Stream(context.Background(), vcnl.StreamConfig{
+				Interval: time.Millisecond,
+				Buffer:   1,
+			})
+
+			if err != nil {
+				t.Fatalf("error starting stream: %v", err)
+			}
+
+			select {
+			case r := <-sp.Readings():
+				if r.Proximity != 1200 || r.Ambient != 300 || r.White != 450 {
+					t.Errorf("unexpected reading: %+v", r)
+				}
+
+				if r.Time.IsZero() {
+					t.Error("reading has no timestamp")
+				}
+
+			case <-time.After(time.Second):
+				t.Fatal("timeout waiting for reading")
+			}
+
+			if err := sp.Close(); err != nil {
+				t.Errorf("error closing stream: %v", err)
+			}
+
+			// drain any reading delivered before closing
+			for range sp.Readings() {
+			}
+
+			if err := sp.Err(); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestStreamDropOldest(t *testing.T) {
+
+	s, dev := connect(t, vcnl.VCNL4040)
+
+	dev.SetProximity(1, 2, 3, 4, 5, 6, 7, 8)
+
+	sp, err := s.Stream(context.Background(), vcnl.StreamConfig{
+		Interval: time.Millisecond,
+		Buffer:   2,
+		Drop:     vcnl.DropOldest,
+	})
+
+	if err != nil {
+		t.Fatalf("error starting stream: %v", err)
+	}
+
+	// let the buffer overflow before receiving
+	deadline := time.Now().Add(time.Second)
+
+	for sp.Dropped() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("timeout waiting for readings to be dropped")
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+
+	first := <-sp.Readings()
+
+	if first.Proximity == 1 {
+		t.Error("oldest reading was not dropped")
+	}
+
+	sp.Close()
+}
+
+func TestStreamStopsOnSensorClose(t *testing.T) {
+
+	s, _ := connect(t, vcnl.VCNL4040)
+
+	sp, err := s.Stream(context.Background(), vcnl.StreamConfig{Interval: time.Millisecond})
+
+	if err != nil {
+		t.Fatalf("error starting stream: %v", err)
+	}
+
+	if err := s.Close(); err != nil {
+		t.Fatalf("error closing sensor: %v", err)
+	}
+
+	select {
+	case <-sp.Done():
+	case <-time.After(time.Second):
+		t.Fatal("stream did not stop on sensor close")
+	}
+
+	if err := sp.Err(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestStreamValidation(t *testing.T) {
+
+	s, _ := connect(t, vcnl.VCNL4040)
+
+	configs := []vcnl.StreamConfig{
+		{Interval: -time.Millisecond},
+		{Buffer: -1},
+		{Drop: vcnl.Block + 1},
+	}
+
+	for _, cfg := range configs {
+
+		_, err := s.Stream(context.Background(), cfg)
+
+		if !errors.Is(err, vcnl.ErrInvalidArgument) {
+			t.Errorf("%+v: expected ErrInvalidArgument, got %v", cfg, err)
+		}
+	}
+}
+
+func TestStreamSkipsFailedRead(t *testing.T) {
+
+	s, dev := connect(t, vcnl.VCNL4040)
+
+	dev.SetProximity(1200)
+
+	// the first sample fails on reading the proximity channel
+	glitch := errors.New("bus glitch")
+	dev.FailNext(1, glitch)
+
+	sp, err := s.Stream(context.Background(), vcnl.StreamConfig{
+		Interval: time.Millisecond,
+		Buffer:   1,
+	})
+
+	if err != nil {
+		t.Fatalf("error starting stream: %v", err)
+	}
+
+	defer sp.Close()
+
+	for i := 0; i < 3; i++ {
+		select {
+		case r, ok := <-sp.Readings():
+			if !ok {
+				t.Fatalf("readings closed after a failed read, error: %v", sp.Err())
+			}
+
+			if r.Proximity != 1200 {
+				t.Errorf("unexpected reading: %+v", r)
+			}
+
+		case <-time.After(time.Second):
+			t.Fatal("timeout waiting for reading")
+		}
+	}
+
+	failed, err := sp.Failed()
+
+	if failed != 1 || !errors.Is(err, glitch) {
+		t.Errorf("got %d failed samples with error %v, want 1 with %v", failed, err, glitch)
+	}
+}
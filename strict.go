@@ -0,0 +1,27 @@
+package vcnl40xx
+
+// EnableStrictMode causes the lenient setters such as SetLEDCurrent,
+// SetIRDutyCycle and SetAmbientIntegrationTime to return an error for
+// unsupported values instead of silently rounding them down to the nearest
+// supported setting
+func (s *Sensor) EnableStrictMode() {
+	s.mu.Lock()
+	s.strict = true
+	s.mu.Unlock()
+}
+
+// DisableStrictMode restores the default behaviour of rounding unsupported
+// setter values down to the nearest supported setting
+func (s *Sensor) DisableStrictMode() {
+	s.mu.Lock()
+	s.strict = false
+	s.mu.Unlock()
+}
+
+// IsStrictMode returns true if strict mode is enabled
+func (s *Sensor) IsStrictMode() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.strict
+}
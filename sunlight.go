@@ -0,0 +1,157 @@
+package vcnl40xx
+
+import (
+	"fmt"
+)
+
+// SunlightCurrent defines the sunlight cancellation current multiplier on
+// the VCNL4030 and VCNL4035
+type SunlightCurrent uint8
+
+const (
+	SunlightCurrent1 SunlightCurrent = 1
+	SunlightCurrent2 SunlightCurrent = 2
+	SunlightCurrent4 SunlightCurrent = 4
+	SunlightCurrent8 SunlightCurrent = 8
+)
+
+// SunlightProtection defines the sunlight protection capability on the
+// VCNL4030 and VCNL4035
+type SunlightProtection uint8
+
+const (
+	// SunlightProtection1 is x1 typical sunlight capability
+	SunlightProtection1 SunlightProtection = 1
+	// SunlightProtection15 is x1.5 typical sunlight capability
+	SunlightProtection15 SunlightProtection = 2
+)
+
+// SunlightOutput defines the proximity output while in sunlight protection
+// mode on the VCNL4030 and VCNL4035
+type SunlightOutput uint8
+
+const (
+	// SunlightOutput00 outputs 0x00 in sunlight protection mode
+	SunlightOutput00 SunlightOutput = 1
+	// SunlightOutputFF outputs 0xFF in sunlight protection mode
+	SunlightOutputFF SunlightOutput = 2
+)
+
+// EnableSunlightCancellation turns on the proximity sunlight cancellation
+// function which reduces false proximity readings in direct sunlight.  When
+// the sensor enters sunlight protection mode FlagSunlightProtect is set in
+// the interrupt flags returned by ReadInterrupts.
+func (s *Sensor) EnableSunlightCancellation() error {
+	return s.bitMask(s.cc.PS_CONF3, LOWER, s.reg.PS_SC_EN_MASK, s.reg.PS_SC_EN_ENABLE)
+}
+
+// DisableSunlightCancellation turns off the proximity sunlight cancellation
+// function
+func (s *Sensor) DisableSunlightCancellation() error {
+	return s.bitMask(s.cc.PS_CONF3, LOWER, s.reg.PS_SC_EN_MASK, s.reg.PS_SC_EN_DISABLE)
+}
+
+// IsSunlightCancellation returns true if sunlight cancellation is enabled
+func (s *Sensor) IsSunlightCancellation() (bool, error) {
+
+	conf3, err := s.readCommandLower(s.cc.PS_CONF3)
+
+	if err != nil {
+		return false, err
+	}
+
+	return conf3&^s.reg.PS_SC_EN_MASK == s.reg.PS_SC_EN_ENABLE, nil
+}
+
+// SetSunlightCurrent sets the sunlight cancellation current on the VCNL4030
+// and VCNL4035
+func (s *Sensor) SetSunlightCurrent(val SunlightCurrent) error {
+
+	if s.model != VCNL4030 && s.model != VCNL4035 {
+		return ErrUnsupportedFeature
+	}
+
+	curValue, ok := lookup(sunlightCurrentOptions(s.reg), uint16(val))
+
+	if !ok {
+		return fmt.Errorf("unknown sunlight cancellation current %d: %w", val, ErrInvalidArgument)
+	}
+
+	return s.bitMask(s.cc.PS_MS, UPPER, s.reg.PS_SC_CUR_MASK, curValue)
+}
+
+// GetSunlightCurrent returns the sunlight cancellation current on the
+// VCNL4030 and VCNL4035
+func (s *Sensor) GetSunlightCurrent() (SunlightCurrent, error) {
+
+	if s.model != VCNL4030 && s.model != VCNL4035 {
+		return 0, ErrUnsupportedFeature
+	}
+
+	v, err := s.getField("sunlight cancellation current", s.cc.PS_MS, UPPER,
+		sunlightCurrentOptions(s.reg), s.reg.PS_SC_CUR_MASK)
+
+	return SunlightCurrent(v), err
+}
+
+// SetSunlightProtection sets the sunlight protection capability on the
+// VCNL4030 and VCNL4035
+func (s *Sensor) SetSunlightProtection(val SunlightProtection) error {
+
+	if s.model != VCNL4030 && s.model != VCNL4035 {
+		return ErrUnsupportedFeature
+	}
+
+	spValue, ok := lookup(sunlightProtectionOptions(s.reg), uint16(val))
+
+	if !ok {
+		return fmt.Errorf("unknown sunlight protection %d: %w", val, ErrInvalidArgument)
+	}
+
+	return s.bitMask(s.cc.PS_MS, UPPER, s.reg.PS_SP_MASK, spValue)
+}
+
+// GetSunlightProtection returns the sunlight protection capability on the
+// VCNL4030 and VCNL4035
+func (s *Sensor) GetSunlightProtection() (SunlightProtection, error) {
+
+	if s.model != VCNL4030 && s.model != VCNL4035 {
+		return 0, ErrUnsupportedFeature
+	}
+
+	v, err := s.getField("sunlight protection", s.cc.PS_MS, UPPER,
+		sunlightProtectionOptions(s.reg), s.reg.PS_SP_MASK)
+
+	return SunlightProtection(v), err
+}
+
+// SetSunlightOutput sets the proximity value output while in sunlight
+// protection mode on the VCNL4030 and VCNL4035
+func (s *Sensor) SetSunlightOutput(val SunlightOutput) error {
+
+	if s.model != VCNL4030 && s.model != VCNL4035 {
+		return ErrUnsupportedFeature
+	}
+
+	spoValue, ok := lookup(sunlightOutputOptions(s.reg), uint16(val))
+
+	if !ok {
+		return fmt.Errorf("unknown sunlight protection output %d: %w", val, ErrInvalidArgument)
+	}
+
+	return s.bitMask(s.cc.PS_MS, UPPER, s.reg.PS_SPO_MASK, spoValue)
+}
+
+// GetSunlightOutput returns the proximity value output while in sunlight
+// protection mode on the VCNL4030 and VCNL4035
+func (s *Sensor) GetSunlightOutput() (SunlightOutput, error) {
+
+	if s.model != VCNL4030 && s.model != VCNL4035 {
+		return 0, ErrUnsupportedFeature
+	}
+
+	v, err := s.getField("sunlight protection output", s.cc.PS_MS, UPPER,
+		sunlightOutputOptions(s.reg), s.reg.PS_SPO_MASK)
+
+	return SunlightOutput(v), err
+}
@@ -0,0 +1,136 @@
+package vcnl40xx
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// proximityT is the approximate duration of one proximity integration time
+// unit (1T)
+const proximityT = 125 * time.Microsecond
+
+// ProximityMultiPulse defines the number of IR LED pulses per proximity
+// measurement on the VCNL4040
+type ProximityMultiPulse uint8
+
+const (
+	ProximityMultiPulse1 ProximityMultiPulse = 1
+	ProximityMultiPulse2 ProximityMultiPulse = 2
+	ProximityMultiPulse4 ProximityMultiPulse = 4
+	ProximityMultiPulse8 ProximityMultiPulse = 8
+)
+
+// SetProximityMultiPulse sets the number of IR LED pulses per proximity
+// measurement on the VCNL4040.  More pulses improve the signal to noise
+// ratio for long range detection at the cost of power and a longer
+// measurement time.
+func (s *Sensor) SetProximityMultiPulse(val ProximityMultiPulse) error {
+
+	if s.model != VCNL4040 {
+		return ErrUnsupportedFeature
+	}
+
+	mpsValue, ok := lookup(proximityMultiPulseOptions(s.reg), uint16(val))
+
+	if !ok {
+		return fmt.Errorf("unknown proximity multi pulse %d: %w", val, ErrInvalidArgument)
+	}
+
+	return s.bitMask(s.cc.PS_CONF3, LOWER, s.reg.PS_MPS_MASK, mpsValue)
+}
+
+// GetProximityMultiPulse returns the number of IR LED pulses per proximity
+// measurement on the VCNL4040
+func (s *Sensor) GetProximityMultiPulse() (ProximityMultiPulse, error) {
+
+	if s.model != VCNL4040 {
+		return 0, ErrUnsupportedFeature
+	}
+
+	v, err := s.getField("proximity multi pulse", s.cc.PS_CONF3, LOWER,
+		proximityMultiPulseOptions(s.reg), s.reg.PS_MPS_MASK)
+
+	return ProximityMultiPulse(v), err
+}
+
+// ProximityMeasurementTime returns the approximate time a single proximity
+// measurement takes for the configured integration time and, on the
+// VCNL4040, multi pulse setting
+func (s *Sensor) ProximityMeasurementTime() (time.Duration, error) {
+	return s.proximityMeasurementTime(context.Background())
+}
+
+// proximityMeasurementTime is ProximityMeasurementTime returning early if ctx
+// is done
+func (s *Sensor) proximityMeasurementTime(ctx context.Context) (time.Duration, error) {
+
+	it, err := s.getFieldContext(ctx, "proximity integration time", s.cc.PS_CONF1,
+		LOWER, proximityITOptions(s.reg), s.reg.PS_IT_MASK)
+
+	if err != nil {
+		return 0, err
+	}
+
+	pulses := uint16(ProximityMultiPulse1)
+
+	if s.model == VCNL4040 {
+		pulses, err = s.getFieldContext(ctx, "proximity multi pulse", s.cc.PS_CONF3,
+			LOWER, proximityMultiPulseOptions(s.reg), s.reg.PS_MPS_MASK)
+
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return time.Duration(it) * time.Duration(pulses) * proximityT / 2, nil
+}
+
+// ProximityPeriod returns the approximate time between proximity
+// measurements in continuous mode, which is the measurement time divided by
+// the IR LED duty cycle
+func (s *Sensor) ProximityPeriod() (time.Duration, error) {
+
+	measure, err := s.ProximityMeasurementTime()
+
+	if err != nil {
+		return 0, err
+	}
+
+	duty, err := s.GetIRDutyCycle()
+
+	if err != nil {
+		return 0, err
+	}
+
+	return measure * time.Duration(duty), nil
+}
+
+// MeasureProximity triggers a single proximity measurement in active force
+// mode, waits for the measurement to complete and returns the value.
+// Active force mode must be enabled first.
+func (s *Sensor) MeasureProximity() (uint16, error) {
+	return s.MeasureProximityContext(context.Background())
+}
+
+// MeasureProximityContext is MeasureProximity returning the context error if
+// ctx is done before the measurement is read
+func (s *Sensor) MeasureProximityContext(ctx context.Context) (uint16, error) {
+
+	wait, err := s.proximityMeasurementTime(ctx)
+
+	if err != nil {
+		return 0, err
+	}
+
+	if err := s.TakeSingleProximityMeasurementContext(ctx); err != nil {
+		return 0, err
+	}
+
+	// allow double the measurement time for LED start up and conversion
+	if err := sleepContext(ctx, 2*wait); err != nil {
+		return 0, err
+	}
+
+	return s.GetProximityContext(ctx)
+}
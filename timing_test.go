@@ -0,0 +1,187 @@
+package vcnl40xx_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	vcnl "github.com/swdee/go-vcnl40xx"
+)
+
+// timingCases are proximity settings with their measurement time and period
+var timingCases = []struct {
+	name   string
+	models []vcnl.Model
+	it     vcnl.ProximityIT
+	mps    vcnl.ProximityMultiPulse
+	duty   uint16
+	// measure is the expected ProximityMeasurementTime
+	measure time.Duration
+	// period is the expected ProximityPeriod
+	period time.Duration
+}{
+	{"1T duty 40", nil, vcnl.ProximityIT1T, 0, 40, 125 * time.Microsecond, 5 * time.Millisecond},
+	{"1.5T duty 80", nil, vcnl.ProximityIT15T, 0, 80, 187500 * time.Nanosecond, 15 * time.Millisecond},
+	{"2T duty 160", nil, vcnl.ProximityIT2T, 0, 160, 250 * time.Microsecond, 40 * time.Millisecond},
+	{"3.5T duty 320", nil, vcnl.ProximityIT35T, 0, 320, 437500 * time.Nanosecond, 140 * time.Millisecond},
+	{"8T duty 40", nil, vcnl.ProximityIT8T, 0, 40, time.Millisecond, 40 * time.Millisecond},
+	{"8T duty 320", nil, vcnl.ProximityIT8T, 0, 320, time.Millisecond, 320 * time.Millisecond},
+	{"1T 1 pulse", only4040, vcnl.ProximityIT1T, vcnl.ProximityMultiPulse1, 40, 125 * time.Microsecond, 5 * time.Millisecond},
+	{"2T 2 pulses", only4040, vcnl.ProximityIT2T, vcnl.ProximityMultiPulse2, 80, 500 * time.Microsecond, 40 * time.Millisecond},
+	{"4T 4 pulses", only4040, vcnl.ProximityIT4T, vcnl.ProximityMultiPulse4, 40, 2 * time.Millisecond, 80 * time.Millisecond},
+	{"8T 8 pulses", only4040, vcnl.ProximityIT8T, vcnl.ProximityMultiPulse8, 160, 8 * time.Millisecond, 1280 * time.Millisecond},
+}
+
+// configureTiming applies the proximity timing settings of a timing case
+func configureTiming(t *testing.T, s *vcnl.Sensor, it vcnl.ProximityIT,
+	mps vcnl.ProximityMultiPulse, duty uint16) {
+
+	t.Helper()
+
+	if err := s.SetProximityIT(it); err != nil {
+		t.Fatalf("error setting integration time: %v", err)
+	}
+
+	if mps != 0 {
+		if err := s.SetProximityMultiPulse(mps); err != nil {
+			t.Fatalf("error setting multi pulse: %v", err)
+		}
+	}
+
+	if err := s.SetIRDutyCycle(duty); err != nil {
+		t.Fatalf("error setting duty cycle: %v", err)
+	}
+}
+
+func TestProximityTiming(t *testing.T) {
+
+	for _, tc := range models {
+		m := tc.model
+
+		for _, c := range timingCases {
+			if !supports(c.models, m) {
+				continue
+			}
+
+			c := c
+
+			t.Run(tc.name+"/"+c.name, func(t *testing.T) {
+
+				s, _ := connect(t, m)
+
+				configureTiming(t, s, c.it, c.mps, c.duty)
+
+				measure, err := s.ProximityMeasurementTime()
+
+				if err != nil {
+					t.Fatalf("error getting measurement time: %v", err)
+				}
+
+				if measure != c.measure {
+					t.Errorf("measurement time %v, want %v", measure, c.measure)
+				}
+
+				period, err := s.ProximityPeriod()
+
+				if err != nil {
+					t.Fatalf("error getting period: %v", err)
+				}
+
+				if period != c.period {
+					t.Errorf("period %v, want %v", period, c.period)
+				}
+			})
+		}
+	}
+}
+
+func TestProximityMultiPulseUnsupported(t *testing.T) {
+
+	for _, m := range newer {
+		s, _ := connect(t, m)
+
+		if err := s.SetProximityMultiPulse(vcnl.ProximityMultiPulse2); !errors.Is(err, vcnl.ErrUnsupportedFeature) {
+			t.Errorf("model %d: expected ErrUnsupportedFeature, got %v", m, err)
+		}
+	}
+}
+
+func TestMeasureProximityContextWait(t *testing.T) {
+
+	for _, tc := range models {
+		m := tc.model
+
+		for _, c := range timingCases {
+			if !supports(c.models, m) {
+				continue
+			}
+
+			c := c
+
+			t.Run(tc.name+"/"+c.name, func(t *testing.T) {
+
+				s, dev := connect(t, m)
+
+				configureTiming(t, s, c.it, c.mps, c.duty)
+
+				if err := s.EnableActiveForceMode(); err != nil {
+					t.Fatalf("error enabling active force mode: %v", err)
+				}
+
+				dev.SetProximity(321)
+
+				start := time.Now()
+				value, err := s.MeasureProximityContext(context.Background())
+				elapsed := time.Since(start)
+
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+
+				if value != 321 {
+					t.Errorf("got %d, want 321", value)
+				}
+
+				// double the measurement time is allowed for conversion
+				if elapsed < 2*c.measure {
+					t.Errorf("measurement returned after %v, want at least %v", elapsed, 2*c.measure)
+				}
+
+				if dev.Triggers() != 1 {
+					t.Errorf("got %d triggers, want 1", dev.Triggers())
+				}
+			})
+		}
+	}
+}
+
+func TestMeasureProximityContextDeadline(t *testing.T) {
+
+	s, dev := connect(t, vcnl.VCNL4040)
+
+	// 8T with 8 pulses waits 16ms for the measurement
+	configureTiming(t, s, vcnl.ProximityIT8T, vcnl.ProximityMultiPulse8, 40)
+
+	if err := s.EnableActiveForceMode(); err != nil {
+		t.Fatalf("error enabling active force mode: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+
+	if _, err := s.MeasureProximityContext(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+
+	// the measurement was triggered but its result never read
+	if dev.Triggers() != 1 {
+		t.Errorf("got %d triggers, want 1", dev.Triggers())
+	}
+
+	for _, tr := range dev.Transactions() {
+		if !tr.Write && tr.Cmd == vcnl.CommandCodes4040().PS_DATA {
+			t.Errorf("PS_DATA read after the context deadline")
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package vcnl40xx
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+const (
+	// gesturePollInterval is the time between checks of the gesture data
+	// ready flag
+	gesturePollInterval = time.Millisecond
+	// gestureTimeout is the maximum time to wait for a gesture measurement
+	gestureTimeout = 100 * time.Millisecond
+)
+
+// GetProximityChannel reads the proximity value of one of the three IR LED
+// channels on the VCNL4035.  valid channels are 1, 2, or 3.
+func (s *Sensor) GetProximityChannel(channel uint8) (uint16, error) {
+	return s.GetProximityChannelContext(context.Background(), channel)
+}
+
+// GetProximityChannelContext is GetProximityChannel returning the context
+// error if ctx is done before the value is read
+func (s *Sensor) GetProximityChannelContext(ctx context.Context, channel uint8) (uint16, error) {
+
+	if s.model != VCNL4035 {
+		return 0, ErrUnsupportedFeature
+	}
+
+	switch channel {
+	case 1:
+		return s.readCommandContext(ctx, s.cc.PS_DATA1)
+	case 2:
+		return s.readCommandContext(ctx, s.cc.PS_DATA2)
+	case 3:
+		return s.readCommandContext(ctx, s.cc.PS_DATA3)
+	default:
+		return 0, fmt.Errorf("invalid proximity channel %d: %w", channel, ErrInvalidArgument)
+	}
+}
+
+// GetProximityChannels reads the proximity values of all three IR LED
+// channels on the VCNL4035
+func (s *Sensor) GetProximityChannels() ([3]uint16, error) {
+	return s.GetProximityChannelsContext(context.Background())
+}
+
+// GetProximityChannelsContext is GetProximityChannels returning the context
+// error if ctx is done before the values are read
+func (s *Sensor) GetProximityChannelsContext(ctx context.Context) ([3]uint16, error) {
+
+	var data [3]uint16
+
+	if s.model != VCNL4035 {
+		return data, ErrUnsupportedFeature
+	}
+
+	for i, commandCode := range []byte{s.cc.PS_DATA1, s.cc.PS_DATA2, s.cc.PS_DATA3} {
+
+		value, err := s.readCommandContext(ctx, commandCode)
+
+		if err != nil {
+			return data, err
+		}
+
+		data[i] = value
+	}
+
+	return data, nil
+}
+
+// EnableGestureMode turns on gesture mode on the VCNL4035.  In gesture mode
+// each proximity measurement drives the three IR LEDs one after another so
+// PS_DATA1, PS_DATA2 and PS_DATA3 are captured in the same measurement cycle.
+// Gesture mode is used together with active force mode and the gesture
+// interrupt, see MeasureGesture.
+func (s *Sensor) EnableGestureMode() error {
+	if s.model != VCNL4035 {
+		return ErrUnsupportedFeature
+	}
+	return s.bitMask(s.cc.PS_CONF3, LOWER, s.reg.GESTURE_MODE_MASK, s.reg.GESTURE_MODE_ENABLE)
+}
+
+// DisableGestureMode turns off gesture mode on the VCNL4035
+func (s *Sensor) DisableGestureMode() error {
+	if s.model != VCNL4035 {
+		return ErrUnsupportedFeature
+	}
+	return s.bitMask(s.cc.PS_CONF3, LOWER, s.reg.GESTURE_MODE_MASK, s.reg.GESTURE_MODE_DISABLE)
+}
+
+// EnableGestureInterrupt sets the INT pin when gesture data is ready on the
+// VCNL4035
+func (s *Sensor) EnableGestureInterrupt() error {
+	if s.model != VCNL4035 {
+		return ErrUnsupportedFeature
+	}
+	return s.bitMask(s.cc.PS_CONF3, LOWER, s.reg.GESTURE_INT_EN_MASK, s.reg.GESTURE_INT_ENABLE)
+}
+
+// DisableGestureInterrupt turns off the gesture data ready interrupt on the
+// VCNL4035
+func (s *Sensor) DisableGestureInterrupt() error {
+	if s.model != VCNL4035 {
+		return ErrUnsupportedFeature
+	}
+	return s.bitMask(s.cc.PS_CONF3, LOWER, s.reg.GESTURE_INT_EN_MASK, s.reg.GESTURE_INT_DISABLE)
+}
+
+// MeasureGesture triggers a single gesture mode measurement on the VCNL4035,
+// waits for the gesture data ready flag and returns the proximity values of
+// all three IR LED channels from that measurement cycle.  Gesture mode, the
+// gesture interrupt and active force mode must be enabled first.  Waiting
+// reads INT_FLAG, which clears any other pending interrupt flags.
+func (s *Sensor) MeasureGesture() ([3]uint16, error) {
+	return s.MeasureGestureContext(context.Background())
+}
+
+// MeasureGestureContext is MeasureGesture returning the context error if ctx
+// is done before the measurement is read
+func (s *Sensor) MeasureGestureContext(ctx context.Context) ([3]uint16, error) {
+
+	if s.model != VCNL4035 {
+		return [3]uint16{}, ErrUnsupportedFeature
+	}
+
+	if err := s.TakeSingleProximityMeasurementContext(ctx); err != nil {
+		return [3]uint16{}, err
+	}
+
+	deadline := time.Now().Add(gestureTimeout)
+
+	for {
+		flags, err := s.ReadInterruptsContext(ctx)
+
+		if err != nil {
+			return [3]uint16{}, err
+		}
+
+		if flags.Has(FlagGestureReady) {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			return [3]uint16{}, fmt.Errorf("timeout waiting for gesture data")
+		}
+
+		if err := sleepContext(ctx, gesturePollInterval); err != nil {
+			return [3]uint16{}, err
+		}
+	}
+
+	return s.GetProximityChannelsContext(ctx)
+}